@@ -0,0 +1,393 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+	"github.com/AlisherGulomzoda/wallet/pkg/wallet"
+)
+
+type cli struct {
+	service *wallet.Service
+	stdout  io.Writer
+	stderr  io.Writer
+	json    bool
+	// usage is the args of the running command.
+	usage string
+}
+
+type command struct {
+	args string
+	help string
+	// changes tells whether the command changes the wallet, which is then
+	// saved to the data dir.
+	changes bool
+	run     func(c *cli, name string, args []string) error
+}
+
+var commands = map[string]command{
+	"register": {
+		args:    "[-currency currency] phone",
+		help:    "register an account",
+		changes: true,
+		run:     register,
+	},
+	"deposit": {
+		args:    "[-currency currency] [-key key] account amount",
+		help:    "deposit the amount in minor units to the account",
+		changes: true,
+		run:     deposit,
+	},
+	"pay": {
+		args:    "[-currency currency] [-key key] account amount category",
+		help:    "pay the amount in minor units from the account",
+		changes: true,
+		run:     pay,
+	},
+	"reject": {
+		args:    "payment",
+		help:    "reject the payment and return its amount",
+		changes: true,
+		run:     reject,
+	},
+	"repeat": {
+		args:    "payment",
+		help:    "make the payment once more",
+		changes: true,
+		run:     repeat,
+	},
+	"favorite": {
+		args:    "payment name",
+		help:    "make a favorite of the payment",
+		changes: true,
+		run:     favorite,
+	},
+	"pay-favorite": {
+		args:    "[-key key] favorite",
+		help:    "pay from the favorite",
+		changes: true,
+		run:     payFavorite,
+	},
+	"show-account": {
+		args: "account",
+		help: "show the account",
+		run:  showAccount,
+	},
+	"list-payments": {
+		args: "account",
+		help: "list the payments of the account",
+		run:  listPayments,
+	},
+	"export": {
+		args: "dir",
+		help: "export the wallet to the dir",
+		run:  export,
+	},
+	"import": {
+		args:    "dir",
+		help:    "import the wallet exported to the dir",
+		changes: true,
+		run:     importDir,
+	},
+}
+
+// parse parses the flags of the command and checks the number of the
+// remaining args.
+func (c *cli) parse(flags *flag.FlagSet, args []string, count int) ([]string, error) {
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: wallet %s %s\n", flags.Name(), c.usage)
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errUsage
+	}
+
+	if flags.NArg() != count {
+		flags.Usage()
+		return nil, errUsage
+	}
+
+	return flags.Args(), nil
+}
+
+func parseAccountID(value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid account %q", value)
+	}
+
+	return id, nil
+}
+
+func parseAmount(value string) (types.Money, error) {
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+
+	return types.Money(amount), nil
+}
+
+func register(c *cli, name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	currency := flags.String("currency", "", "currency of the account, TJS by default")
+	args, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	var account *types.Account
+	if *currency == "" {
+		account, err = c.service.RegisterAccount(types.Phone(args[0]))
+	} else {
+		account, err = c.service.OpenAccount(types.Phone(args[0]), types.Currency(*currency))
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.printAccount(account)
+}
+
+func deposit(c *cli, name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	currency := flags.String("currency", "", "currency of the amount, the account's by default")
+	key := flags.String("key", "", "idempotency key")
+	args, err := c.parse(flags, args, 2)
+	if err != nil {
+		return err
+	}
+
+	accountID, err := parseAccountID(args[0])
+	if err != nil {
+		return err
+	}
+
+	amount, err := parseAmount(args[1])
+	if err != nil {
+		return err
+	}
+
+	err = c.service.Deposit(accountID, amount, wallet.PayOptions(types.Currency(*currency), *key)...)
+	if err != nil {
+		return err
+	}
+
+	account, err := c.service.FindAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	return c.printAccount(account)
+}
+
+func pay(c *cli, name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	currency := flags.String("currency", "", "currency of the amount, the account's by default")
+	key := flags.String("key", "", "idempotency key")
+	args, err := c.parse(flags, args, 3)
+	if err != nil {
+		return err
+	}
+
+	accountID, err := parseAccountID(args[0])
+	if err != nil {
+		return err
+	}
+
+	amount, err := parseAmount(args[1])
+	if err != nil {
+		return err
+	}
+
+	payment, err := c.service.Pay(accountID, amount, types.PaymentCategoty(args[2]), wallet.PayOptions(types.Currency(*currency), *key)...)
+	if err != nil {
+		return err
+	}
+
+	return c.printPayment(payment)
+}
+
+func reject(c *cli, name string, args []string) error {
+	args, err := c.parse(flag.NewFlagSet(name, flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	err = c.service.Reject(args[0])
+	if err != nil {
+		return err
+	}
+
+	payment, err := c.service.FindPaymetByID(args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.printPayment(payment)
+}
+
+func repeat(c *cli, name string, args []string) error {
+	args, err := c.parse(flag.NewFlagSet(name, flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	payment, err := c.service.Repeat(args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.printPayment(payment)
+}
+
+func favorite(c *cli, name string, args []string) error {
+	args, err := c.parse(flag.NewFlagSet(name, flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+
+	favorite, err := c.service.FavoritePayment(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(favorite)
+	}
+
+	_, err = fmt.Fprintf(c.stdout, "Favorite %s %q: account %d, %d %s for %s\n",
+		favorite.ID, favorite.Name, favorite.AccountID, favorite.Amount, favorite.Currency, favorite.Category)
+	return err
+}
+
+func payFavorite(c *cli, name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	key := flags.String("key", "", "idempotency key")
+	args, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	payment, err := c.service.PayFromFavorite(args[0], wallet.PayOptions("", *key)...)
+	if err != nil {
+		return err
+	}
+
+	return c.printPayment(payment)
+}
+
+func showAccount(c *cli, name string, args []string) error {
+	args, err := c.parse(flag.NewFlagSet(name, flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	accountID, err := parseAccountID(args[0])
+	if err != nil {
+		return err
+	}
+
+	account, err := c.service.FindAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	return c.printAccount(account)
+}
+
+func listPayments(c *cli, name string, args []string) error {
+	args, err := c.parse(flag.NewFlagSet(name, flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	accountID, err := parseAccountID(args[0])
+	if err != nil {
+		return err
+	}
+
+	payments, err := c.service.AccountPayments(accountID)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(payments)
+	}
+
+	for i := range payments {
+		err = c.printPayment(&payments[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func export(c *cli, name string, args []string) error {
+	args, err := c.parse(flag.NewFlagSet(name, flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(args[0], 0755)
+	if err != nil {
+		return err
+	}
+
+	return c.service.Export(args[0])
+}
+
+func importDir(c *cli, name string, args []string) error {
+	args, err := c.parse(flag.NewFlagSet(name, flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.service.Import(args[0])
+}
+
+func (c *cli) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
+
+func (c *cli) printAccount(account *types.Account) error {
+	if c.json {
+		return c.printJSON(account)
+	}
+
+	_, err := fmt.Fprintf(c.stdout, "Account %d %s: balance %d %s, %s\n",
+		account.ID, account.Phone, account.Balance, account.Currency, account.Status)
+	return err
+}
+
+func (c *cli) printPayment(payment *types.Payment) error {
+	if c.json {
+		return c.printJSON(payment)
+	}
+
+	_, err := fmt.Fprintf(c.stdout, "Payment %s: account %d, %s %d %s for %s, %s\n",
+		payment.ID, payment.AccountID, payment.Type, payment.Amount, payment.Currency, payment.Category, payment.Status)
+	return err
+}
@@ -0,0 +1,132 @@
+// Command wallet runs support operations against a wallet kept in a data
+// dir in the format written by wallet.Service.Export.
+//
+//	wallet [-data dir] [-json] <command> [flags] [args]
+//
+// The data dir is $WALLET_DATA or the current dir by default. A command
+// which changes the wallet exports it back to the data dir.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/wallet"
+)
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	// The wallet logs every failure on its own, the errors are reported
+	// here instead.
+	log.SetOutput(ioutil.Discard)
+
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "wallet:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("wallet", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	data := flags.String("data", defaultData(), "data dir of the wallet")
+	asJSON := flags.Bool("json", false, "print JSON instead of text")
+	flags.Usage = func() {
+		usage(flags)
+	}
+
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return err
+	}
+	if err != nil {
+		return errUsage
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	name := flags.Arg(0)
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "wallet: unknown command %q\n", name)
+		flags.Usage()
+		return errUsage
+	}
+
+	c := &cli{
+		service: wallet.NewService(wallet.NewMemoryRepository()),
+		stdout:  stdout,
+		stderr:  stderr,
+		json:    *asJSON,
+		usage:   command.args,
+	}
+
+	err = c.service.Import(*data)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", *data, err)
+	}
+
+	err = command.run(c, name, flags.Args()[1:])
+	if err != nil {
+		return err
+	}
+
+	if !command.changes {
+		return nil
+	}
+
+	err = os.MkdirAll(*data, 0755)
+	if err != nil {
+		return err
+	}
+
+	err = c.service.Export(*data)
+	if err != nil {
+		return fmt.Errorf("save %s: %w", *data, err)
+	}
+
+	return nil
+}
+
+func defaultData() string {
+	data := os.Getenv("WALLET_DATA")
+	if data == "" {
+		return "."
+	}
+
+	return data
+}
+
+func usage(flags *flag.FlagSet) {
+	out := flags.Output()
+	fmt.Fprintln(out, "usage: wallet [-data dir] [-json] <command> [flags] [args]")
+	flags.PrintDefaults()
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(out, "\ncommands:")
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s %s\n", name, commands[name].args)
+		fmt.Fprintf(out, "    \t%s\n", commands[name].help)
+	}
+}
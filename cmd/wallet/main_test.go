@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+type testCLI struct {
+	t    *testing.T
+	data string
+}
+
+// run runs the command against the data dir and returns its output.
+func (c *testCLI) run(args ...string) (string, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err := run(append([]string{"-data", c.data}, args...), stdout, stderr)
+
+	return stdout.String(), err
+}
+
+func (c *testCLI) mustRun(args ...string) string {
+	out, err := c.run(args...)
+	if err != nil {
+		c.t.Fatalf("%v: %v", args, err)
+	}
+
+	return out
+}
+
+func newTestCLI(t *testing.T) (*testCLI, func()) {
+	data, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCLI{t: t, data: data}, func() {
+		os.RemoveAll(data)
+	}
+}
+
+func TestRun_success(t *testing.T) {
+	c, cleanup := newTestCLI(t)
+	defer cleanup()
+
+	out := c.mustRun("register", "+992000000001")
+	if out != "Account 1 +992000000001: balance 0 TJS, ACTIVE\n" {
+		t.Errorf("invalid register output: %q", out)
+	}
+
+	c.mustRun("deposit", "1", "1000")
+
+	var payment types.Payment
+	err := json.Unmarshal([]byte(c.mustRun("-json", "pay", "-key", "order-1", "1", "100", "auto")), &payment)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	out = c.mustRun("pay", "-key", "order-1", "1", "100", "auto")
+	if !strings.HasPrefix(out, "Payment "+payment.ID+":") {
+		t.Errorf("payment not replayed: %q", out)
+	}
+
+	out = c.mustRun("favorite", payment.ID, "car")
+	fields := strings.Fields(out)
+	if len(fields) < 2 || !strings.Contains(out, `"car": account 1, 100 TJS for auto`) {
+		t.Errorf("invalid favorite output: %q", out)
+		return
+	}
+
+	c.mustRun("pay-favorite", fields[1])
+	c.mustRun("repeat", payment.ID)
+	c.mustRun("reject", payment.ID)
+
+	var payments []types.Payment
+	err = json.Unmarshal([]byte(c.mustRun("-json", "list-payments", "1")), &payments)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(payments) != 3 {
+		t.Errorf("invalid payments: %v", payments)
+	}
+
+	out = c.mustRun("show-account", "1")
+	if out != "Account 1 +992000000001: balance 800 TJS, ACTIVE\n" {
+		t.Errorf("invalid account output: %q", out)
+	}
+}
+
+func TestRun_exportImport(t *testing.T) {
+	c, cleanup := newTestCLI(t)
+	defer cleanup()
+
+	c.mustRun("register", "+992000000001")
+	c.mustRun("deposit", "1", "1000")
+
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	c.mustRun("export", dir)
+
+	other, cleanupOther := newTestCLI(t)
+	defer cleanupOther()
+
+	other.mustRun("import", dir)
+	out := other.mustRun("show-account", "1")
+	if out != "Account 1 +992000000001: balance 1000 TJS, ACTIVE\n" {
+		t.Errorf("invalid imported account: %q", out)
+	}
+}
+
+func TestRun_fail(t *testing.T) {
+	c, cleanup := newTestCLI(t)
+	defer cleanup()
+
+	_, err := c.run()
+	if !errors.Is(err, errUsage) {
+		t.Errorf("no command: invalid error %v", err)
+	}
+
+	_, err = c.run("unknown")
+	if !errors.Is(err, errUsage) {
+		t.Errorf("unknown command: invalid error %v", err)
+	}
+
+	_, err = c.run("pay", "1")
+	if !errors.Is(err, errUsage) {
+		t.Errorf("missing args: invalid error %v", err)
+	}
+
+	_, err = c.run("deposit", "x", "1")
+	if err == nil {
+		t.Error("invalid account accepted")
+	}
+
+	_, err = c.run("show-account", "1")
+	if err == nil || !strings.Contains(err.Error(), "account not found") {
+		t.Errorf("missing account: invalid error %v", err)
+	}
+
+	_, err = c.run("import", c.data+"/missing")
+	if err == nil {
+		t.Error("missing dir imported")
+	}
+}
@@ -0,0 +1,164 @@
+// Command walletd serves the wallet over an HTTP JSON API and, with the
+// -grpc flag, over gRPC. With the -data flag every change is logged to the
+// data dir before it is acknowledged and is recovered on start, the wallet
+// is kept in memory only otherwise.
+//
+// The events of the outbox are relayed to the log or, with -relay
+// telegram, to a Telegram chat. With the -replay flag the relay delivers
+// the events again from the given seq on start.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/grpcapi"
+	"github.com/AlisherGulomzoda/wallet/pkg/httpapi"
+	"github.com/AlisherGulomzoda/wallet/pkg/telegram"
+	"github.com/AlisherGulomzoda/wallet/pkg/wallet"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	grpcAddr := flag.String("grpc", "", "address to serve gRPC on, disabled when empty")
+	data := flag.String("data", "", "dir to keep the wallet in")
+	relayTo := flag.String("relay", "log", "where to relay the events of the outbox: log, telegram, or none")
+	token := flag.String("telegram-token", os.Getenv("TELEGRAM_TOKEN"), "token of the Telegram bot for -relay telegram")
+	chatID := flag.Int64("telegram-chat", 0, "Telegram chat to relay the events to")
+	replay := flag.Int64("replay", 0, "deliver the events of the outbox again from this seq on start")
+	flag.Parse()
+
+	var sink wallet.Sink
+	switch *relayTo {
+	case "log":
+		sink = wallet.WriterSink(os.Stderr)
+	case "telegram":
+		if *token == "" || *chatID == 0 {
+			log.Fatal("-relay telegram needs -telegram-token and -telegram-chat")
+		}
+		sink = wallet.MessengerSink(telegram.NewClient(*token, *chatID))
+	case "none":
+		if *replay != 0 {
+			log.Fatal("-replay needs a relay")
+		}
+	default:
+		log.Fatalf("unknown relay %q", *relayTo)
+	}
+
+	var repository wallet.Repository = wallet.NewMemoryRepository()
+	var files *wallet.FileRepository
+	if *data != "" {
+		var err error
+		files, err = wallet.OpenFileRepository(*data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		repository = files
+	}
+
+	bus := wallet.NewEventBus()
+	service := wallet.NewService(repository, wallet.WithEventBus(bus))
+
+	stopRelay := make(chan struct{})
+	relayDone := make(chan struct{})
+	if sink == nil {
+		close(relayDone)
+	} else {
+		relay := service.NewRelay(*relayTo, sink)
+		go func() {
+			defer close(relayDone)
+			if *replay != 0 {
+				delivered, err := relay.Replay(*replay)
+				log.Printf("relay %s: replayed %d events from %d", *relayTo, delivered, *replay)
+				if err != nil {
+					log.Printf("relay %s: %v", *relayTo, err)
+				}
+			}
+			relay.Run(time.Second, stopRelay)
+		}()
+	}
+
+	server := &http.Server{
+		Addr:         *addr,
+		Handler:      httpapi.NewHandler(service),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	grpcServer := grpc.NewServer()
+	grpcapi.NewServer(service, bus).Register(grpcServer)
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		go func() {
+			log.Printf("serving gRPC on %s", *grpcAddr)
+			err := grpcServer.Serve(listener)
+			if err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		err := server.Shutdown(ctx)
+		if err != nil {
+			log.Print(err)
+		}
+
+		// Streams never end by themselves, so they are cut after the
+		// timeout.
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}()
+
+	log.Printf("listening on %s", *addr)
+	err := server.ListenAndServe()
+	if err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-done
+
+	close(stopRelay)
+	<-relayDone
+
+	if files != nil {
+		err = files.Snapshot()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = files.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
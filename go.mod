@@ -1,5 +1,16 @@
 module github.com/AlisherGulomzoda/wallet
 
-go 1.15
+go 1.23
 
-require github.com/google/uuid v1.1.2
+require (
+	github.com/google/uuid v1.6.0
+	google.golang.org/grpc v1.64.0
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	golang.org/x/net v0.22.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+)
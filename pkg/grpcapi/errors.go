@@ -0,0 +1,45 @@
+package grpcapi
+
+import (
+	"errors"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+	"github.com/AlisherGulomzoda/wallet/pkg/wallet"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorCodes maps errors of the wallet to gRPC codes. The first match
+// wins.
+var errorCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{wallet.ErrAccountNotFound, codes.NotFound},
+	{wallet.ErrPaymentNotFound, codes.NotFound},
+	{wallet.ErrFavoriteNotFound, codes.NotFound},
+	{wallet.ErrPhoneAlreadyRegitered, codes.AlreadyExists},
+	{wallet.ErrIdempotencyKeyReused, codes.AlreadyExists},
+	{wallet.ErrInvalidTransition, codes.FailedPrecondition},
+	{wallet.ErrPaymentRefunded, codes.FailedPrecondition},
+	{wallet.ErrAccountFrozen, codes.FailedPrecondition},
+	{wallet.ErrAccountClosed, codes.FailedPrecondition},
+	{wallet.ErrBalanceNotAmount, codes.FailedPrecondition},
+	{wallet.ErrLimitExceeded, codes.FailedPrecondition},
+	{wallet.ErrRefundExceedsAmount, codes.FailedPrecondition},
+	{wallet.ErrAmountMustGreateZero, codes.InvalidArgument},
+	{wallet.ErrInvalidPhone, codes.InvalidArgument},
+	{wallet.ErrTransferToSameAccount, codes.InvalidArgument},
+	{wallet.ErrRateNotFound, codes.InvalidArgument},
+	{types.ErrCurrencyMismatch, codes.InvalidArgument},
+}
+
+func statusError(err error) error {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return status.Error(e.code, err.Error())
+		}
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,305 @@
+// Package grpcapi exposes a wallet.Service over gRPC. The service is
+// defined in proto/wallet/v1/wallet.proto, the code in walletpb is
+// generated from it with protoc-gen-go and protoc-gen-go-grpc.
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=walletpb --go_opt=paths=source_relative --go-grpc_out=walletpb --go-grpc_opt=paths=source_relative wallet/v1/wallet.proto
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/grpcapi/walletpb"
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+	"github.com/AlisherGulomzoda/wallet/pkg/wallet"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchBuffer is the number of events buffered for a WatchPayments
+// stream.
+const watchBuffer = 100
+
+type Server struct {
+	walletpb.UnimplementedWalletServer
+
+	service *wallet.Service
+	events  *wallet.EventBus
+}
+
+// NewServer wraps the service. WatchPayments streams the events of the
+// bus, which must be the one the service publishes to, and is not
+// available when the bus is nil.
+func NewServer(service *wallet.Service, events *wallet.EventBus) *Server {
+	return &Server{service: service, events: events}
+}
+
+// Register registers the server on the gRPC server.
+func (s *Server) Register(server *grpc.Server) {
+	walletpb.RegisterWalletServer(server, s)
+}
+
+func (s *Server) RegisterAccount(_ context.Context, request *walletpb.RegisterAccountRequest) (*walletpb.Account, error) {
+	var account *types.Account
+	var err error
+	if request.Currency == "" {
+		account, err = s.service.RegisterAccount(types.Phone(request.Phone))
+	} else {
+		account, err = s.service.OpenAccount(types.Phone(request.Phone), types.Currency(request.Currency))
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return accountOf(account), nil
+}
+
+func (s *Server) GetAccount(_ context.Context, request *walletpb.GetAccountRequest) (*walletpb.Account, error) {
+	account, err := s.service.FindAccountByID(request.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return accountOf(account), nil
+}
+
+func (s *Server) Deposit(ctx context.Context, request *walletpb.DepositRequest) (*walletpb.Account, error) {
+	err := s.service.Deposit(request.AccountId, types.Money(request.Amount), wallet.PayOptions(types.Currency(request.Currency), request.IdempotencyKey)...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return s.GetAccount(ctx, &walletpb.GetAccountRequest{Id: request.AccountId})
+}
+
+func (s *Server) Pay(_ context.Context, request *walletpb.PayRequest) (*walletpb.Payment, error) {
+	options := wallet.PayOptions(types.Currency(request.Currency), request.IdempotencyKey)
+	payment, err := s.service.Pay(request.AccountId, types.Money(request.Amount), types.PaymentCategoty(request.Category), options...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return paymentOf(payment), nil
+}
+
+func (s *Server) GetPayment(_ context.Context, request *walletpb.GetPaymentRequest) (*walletpb.Payment, error) {
+	payment, err := s.service.FindPaymetByID(request.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return paymentOf(payment), nil
+}
+
+func (s *Server) ListPayments(_ context.Context, request *walletpb.ListPaymentsRequest) (*walletpb.ListPaymentsResponse, error) {
+	payments, err := s.service.AccountPayments(request.AccountId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &walletpb.ListPaymentsResponse{Payments: make([]*walletpb.Payment, len(payments))}
+	for i := range payments {
+		response.Payments[i] = paymentOf(&payments[i])
+	}
+
+	return response, nil
+}
+
+func (s *Server) RejectPayment(ctx context.Context, request *walletpb.RejectPaymentRequest) (*walletpb.Payment, error) {
+	err := s.service.Reject(request.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return s.GetPayment(ctx, &walletpb.GetPaymentRequest{Id: request.Id})
+}
+
+func (s *Server) RepeatPayment(_ context.Context, request *walletpb.RepeatPaymentRequest) (*walletpb.Payment, error) {
+	payment, err := s.service.Repeat(request.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return paymentOf(payment), nil
+}
+
+func (s *Server) FavoritePayment(_ context.Context, request *walletpb.FavoritePaymentRequest) (*walletpb.Favorite, error) {
+	favorite, err := s.service.FavoritePayment(request.PaymentId, request.Name)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return favoriteOf(favorite), nil
+}
+
+func (s *Server) GetFavorite(_ context.Context, request *walletpb.GetFavoriteRequest) (*walletpb.Favorite, error) {
+	favorite, err := s.service.FindFavoriteByID(request.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return favoriteOf(favorite), nil
+}
+
+func (s *Server) PayFromFavorite(_ context.Context, request *walletpb.PayFromFavoriteRequest) (*walletpb.Payment, error) {
+	payment, err := s.service.PayFromFavorite(request.FavoriteId, wallet.PayOptions("", request.IdempotencyKey)...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return paymentOf(payment), nil
+}
+
+func (s *Server) SumPayments(_ *walletpb.SumPaymentsRequest, stream walletpb.Wallet_SumPaymentsServer) error {
+	var err error
+	for progress := range s.service.SumPaymentsWithProgress() {
+		// The channel is drained after a failed send, so the summing
+		// goroutines don't block forever.
+		if err != nil {
+			continue
+		}
+
+		err = stream.Send(&walletpb.SumProgress{
+			Part:     int64(progress.Part),
+			Currency: string(progress.Currency),
+			Result:   int64(progress.Result),
+		})
+	}
+
+	return err
+}
+
+func (s *Server) WatchPayments(request *walletpb.WatchPaymentsRequest, stream walletpb.Wallet_WatchPaymentsServer) error {
+	if s.events == nil {
+		return status.Error(codes.Unimplemented, "payment events are not published")
+	}
+
+	subscription := s.events.Subscribe(watchBuffer)
+	defer subscription.Unsubscribe()
+
+	// The headers are sent once the subscription is made, so the client
+	// knows it won't miss events published after that.
+	err := stream.SendHeader(nil)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case event := <-subscription.Events():
+			if subscription.Dropped() != 0 {
+				return status.Errorf(codes.ResourceExhausted, "%d events dropped", subscription.Dropped())
+			}
+
+			if request.AccountId != 0 && event.Header().AccountID != request.AccountId {
+				continue
+			}
+
+			message := paymentEventOf(event)
+			if message == nil {
+				continue
+			}
+
+			err := stream.Send(message)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func timestampOf(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+func accountOf(account *types.Account) *walletpb.Account {
+	return &walletpb.Account{
+		Id:        account.ID,
+		Phone:     string(account.Phone),
+		Balance:   int64(account.Balance),
+		Currency:  string(account.Currency),
+		Status:    string(account.Status),
+		Overdraft: int64(account.Overdraft),
+		CreatedAt: timestampOf(account.CreatedAt),
+		UpdatedAt: timestampOf(account.UpdatedAt),
+	}
+}
+
+func paymentOf(payment *types.Payment) *walletpb.Payment {
+	return &walletpb.Payment{
+		Id:               payment.ID,
+		AccountId:        payment.AccountID,
+		Amount:           int64(payment.Amount),
+		Currency:         string(payment.Currency),
+		Category:         string(payment.Category),
+		Status:           string(payment.Status),
+		Type:             string(payment.Type),
+		LinkedId:         payment.LinkedID,
+		Refunded:         int64(payment.Refunded),
+		OriginalAmount:   int64(payment.OriginalAmount),
+		OriginalCurrency: string(payment.OriginalCurrency),
+		Rate:             int64(payment.Rate),
+		CreatedAt:        timestampOf(payment.CreatedAt),
+		UpdatedAt:        timestampOf(payment.UpdatedAt),
+	}
+}
+
+func favoriteOf(favorite *types.Favorite) *walletpb.Favorite {
+	return &walletpb.Favorite{
+		Id:        favorite.ID,
+		AccountId: favorite.AccountID,
+		Name:      favorite.Name,
+		Amount:    int64(favorite.Amount),
+		Currency:  string(favorite.Currency),
+		Category:  string(favorite.Category),
+		CreatedAt: timestampOf(favorite.CreatedAt),
+		UpdatedAt: timestampOf(favorite.UpdatedAt),
+	}
+}
+
+// paymentEventOf returns nil for the events which are not about payments.
+func paymentEventOf(event wallet.Event) *walletpb.PaymentEvent {
+	header := event.Header()
+	message := &walletpb.PaymentEvent{
+		Id:        header.ID,
+		Name:      event.EventName(),
+		AccountId: header.AccountID,
+		Time:      timestampOf(header.Time),
+	}
+
+	switch event := event.(type) {
+	case *wallet.PaymentCreated:
+		message.PaymentId = event.PaymentID
+		message.Type = string(event.Type)
+		message.Category = string(event.Category)
+		message.LinkedId = event.LinkedID
+		message.Amount = int64(event.Amount)
+		message.Currency = string(event.Currency)
+		message.Balance = int64(event.Balance)
+	case *wallet.PaymentConfirmed:
+		message.PaymentId = event.PaymentID
+	case *wallet.PaymentRejected:
+		message.PaymentId = event.PaymentID
+		message.Amount = int64(event.Amount)
+		message.Currency = string(event.Currency)
+		message.Balance = int64(event.Balance)
+	case *wallet.PaymentRefunded:
+		message.PaymentId = event.PaymentID
+		message.Amount = int64(event.Amount)
+		message.Currency = string(event.Currency)
+		message.Refunded = int64(event.Refunded)
+		message.Balance = int64(event.Balance)
+	default:
+		return nil
+	}
+
+	return message
+}
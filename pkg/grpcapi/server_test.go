@@ -0,0 +1,265 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/grpcapi/walletpb"
+	"github.com/AlisherGulomzoda/wallet/pkg/wallet"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testClient struct {
+	walletpb.WalletClient
+	service *wallet.Service
+}
+
+// newTestClient serves a new wallet in-process and returns a client of
+// it. The returned func stops the server.
+func newTestClient(t *testing.T) (*testClient, func()) {
+	listener := bufconn.Listen(1 << 20)
+	bus := wallet.NewEventBus()
+	service := wallet.NewService(wallet.NewMemoryRepository(), wallet.WithEventBus(bus))
+
+	server := grpc.NewServer()
+	NewServer(service, bus).Register(server)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}
+	conn, err := grpc.NewClient("passthrough:///bufnet", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &testClient{WalletClient: walletpb.NewWalletClient(conn), service: service}
+	return client, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func (c *testClient) addAccount(t *testing.T, balance int64) *walletpb.Account {
+	ctx := context.Background()
+	account, err := c.RegisterAccount(ctx, &walletpb.RegisterAccountRequest{Phone: "+992000000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account, err = c.Deposit(ctx, &walletpb.DepositRequest{AccountId: account.Id, Amount: balance})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return account
+}
+
+func TestServer_success(t *testing.T) {
+	c, stop := newTestClient(t)
+	defer stop()
+	ctx := context.Background()
+
+	account := c.addAccount(t, 1_000)
+	if account.Balance != 1_000 || account.Phone != "+992000000001" || account.CreatedAt == nil {
+		t.Errorf("invalid account: %v", account)
+		return
+	}
+
+	payment, err := c.Pay(ctx, &walletpb.PayRequest{AccountId: account.Id, Amount: 100, Category: "auto"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	repeated, err := c.RepeatPayment(ctx, &walletpb.RepeatPaymentRequest{Id: payment.Id})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	rejected, err := c.RejectPayment(ctx, &walletpb.RejectPaymentRequest{Id: payment.Id})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if rejected.Status != "FAIL" {
+		t.Errorf("invalid rejected payment: %v", rejected)
+	}
+
+	favorite, err := c.FavoritePayment(ctx, &walletpb.FavoritePaymentRequest{PaymentId: repeated.Id, Name: "car"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = c.PayFromFavorite(ctx, &walletpb.PayFromFavoriteRequest{FavoriteId: favorite.Id})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	list, err := c.ListPayments(ctx, &walletpb.ListPaymentsRequest{AccountId: account.Id})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(list.Payments) != 3 {
+		t.Errorf("invalid payments: %v", list.Payments)
+	}
+
+	account, err = c.GetAccount(ctx, &walletpb.GetAccountRequest{Id: account.Id})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if account.Balance != 800 {
+		t.Errorf("invalid balance: %v", account.Balance)
+	}
+}
+
+func TestServer_fail(t *testing.T) {
+	c, stop := newTestClient(t)
+	defer stop()
+	ctx := context.Background()
+	account := c.addAccount(t, 1_000)
+
+	_, err := c.GetAccount(ctx, &walletpb.GetAccountRequest{Id: 2})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("get account: invalid error %v", err)
+	}
+
+	_, err = c.RegisterAccount(ctx, &walletpb.RegisterAccountRequest{Phone: "+992000000001"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("register account: invalid error %v", err)
+	}
+
+	_, err = c.Pay(ctx, &walletpb.PayRequest{AccountId: account.Id, Amount: -1, Category: "auto"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("pay: invalid error %v", err)
+	}
+
+	_, err = c.Pay(ctx, &walletpb.PayRequest{AccountId: account.Id, Amount: 2_000, Category: "auto"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("pay: invalid error %v", err)
+	}
+
+	_, err = c.RejectPayment(ctx, &walletpb.RejectPaymentRequest{Id: "x"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("reject payment: invalid error %v", err)
+	}
+}
+
+func TestServer_SumPayments(t *testing.T) {
+	c, stop := newTestClient(t)
+	defer stop()
+	ctx := context.Background()
+	account := c.addAccount(t, 1_000)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.Pay(ctx, &walletpb.PayRequest{AccountId: account.Id, Amount: 100, Category: "auto"})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	stream, err := c.SumPayments(ctx, &walletpb.SumPaymentsRequest{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var sum int64
+	for {
+		progress, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		sum += progress.Result
+	}
+
+	if sum != 300 {
+		t.Errorf("invalid sum: %v", sum)
+	}
+}
+
+func TestServer_WatchPayments(t *testing.T) {
+	c, stop := newTestClient(t)
+	defer stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	account := c.addAccount(t, 1_000)
+
+	stream, err := c.WatchPayments(ctx, &walletpb.WatchPaymentsRequest{AccountId: account.Id})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = stream.Header()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment, err := c.Pay(ctx, &walletpb.PayRequest{AccountId: account.Id, Amount: 100, Category: "auto"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = c.service.Freeze(account.Id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = c.service.Unfreeze(account.Id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = c.RejectPayment(ctx, &walletpb.RejectPaymentRequest{Id: payment.Id})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	created, err := stream.Recv()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if created.Name != "PaymentCreated" || created.PaymentId != payment.Id || created.Balance != 900 || created.Id == "" || created.Time == nil {
+		t.Errorf("invalid event: %v", created)
+	}
+
+	rejected, err := stream.Recv()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if rejected.Name != "PaymentRejected" || rejected.PaymentId != payment.Id || rejected.Balance != 1_000 {
+		t.Errorf("invalid event: %v", rejected)
+	}
+
+	cancel()
+	_, err = stream.Recv()
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("invalid error after cancel: %v", err)
+	}
+}
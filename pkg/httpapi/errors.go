@@ -0,0 +1,48 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+	"github.com/AlisherGulomzoda/wallet/pkg/wallet"
+)
+
+var errBadRequest = errors.New("bad request")
+
+// errorStatuses maps errors of the wallet to HTTP statuses and to codes
+// which clients can rely on. The first match wins.
+var errorStatuses = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{errBadRequest, http.StatusBadRequest, "bad_request"},
+	{wallet.ErrAccountNotFound, http.StatusNotFound, "account_not_found"},
+	{wallet.ErrPaymentNotFound, http.StatusNotFound, "payment_not_found"},
+	{wallet.ErrFavoriteNotFound, http.StatusNotFound, "favorite_not_found"},
+	{wallet.ErrPhoneAlreadyRegitered, http.StatusConflict, "phone_already_registered"},
+	{wallet.ErrIdempotencyKeyReused, http.StatusConflict, "idempotency_key_reused"},
+	{wallet.ErrInvalidTransition, http.StatusConflict, "invalid_transition"},
+	{wallet.ErrPaymentRefunded, http.StatusConflict, "payment_refunded"},
+	{wallet.ErrAccountFrozen, http.StatusForbidden, "account_frozen"},
+	{wallet.ErrAccountClosed, http.StatusForbidden, "account_closed"},
+	{wallet.ErrAmountMustGreateZero, http.StatusUnprocessableEntity, "invalid_amount"},
+	{wallet.ErrBalanceNotAmount, http.StatusUnprocessableEntity, "insufficient_balance"},
+	{wallet.ErrLimitExceeded, http.StatusUnprocessableEntity, "limit_exceeded"},
+	{wallet.ErrRefundExceedsAmount, http.StatusUnprocessableEntity, "refund_exceeds_amount"},
+	{wallet.ErrInvalidPhone, http.StatusUnprocessableEntity, "invalid_phone"},
+	{wallet.ErrTransferToSameAccount, http.StatusUnprocessableEntity, "transfer_to_same_account"},
+	{wallet.ErrRateNotFound, http.StatusUnprocessableEntity, "rate_not_found"},
+	{types.ErrCurrencyMismatch, http.StatusUnprocessableEntity, "currency_mismatch"},
+}
+
+func statusOf(err error) (int, string) {
+	for _, e := range errorStatuses {
+		if errors.Is(err, e.err) {
+			return e.status, e.code
+		}
+	}
+
+	return http.StatusInternalServerError, "internal"
+}
@@ -0,0 +1,285 @@
+// Package httpapi exposes a wallet.Service as a JSON API over HTTP.
+//
+//	POST /accounts                    register an account
+//	GET  /accounts/{id}               find an account
+//	POST /accounts/{id}/deposit       deposit to an account
+//	POST /accounts/{id}/payments      pay from an account
+//	GET  /accounts/{id}/payments      list payments of an account
+//	GET  /payments/{id}               find a payment
+//	POST /payments/{id}/reject        reject a payment
+//	POST /payments/{id}/repeat        repeat a payment
+//	POST /payments/{id}/favorite      make a favorite of a payment
+//	GET  /favorites/{id}              find a favorite
+//	POST /favorites/{id}/pay          pay from a favorite
+//
+// Money-moving requests accept an Idempotency-Key header.
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+	"github.com/AlisherGulomzoda/wallet/pkg/wallet"
+)
+
+type handler struct {
+	service *wallet.Service
+}
+
+func NewHandler(service *wallet.Service) http.Handler {
+	h := &handler{service: service}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/accounts", h.accounts)
+	mux.HandleFunc("/accounts/", h.account)
+	mux.HandleFunc("/payments/", h.payment)
+	mux.HandleFunc("/favorites/", h.favorite)
+
+	return mux
+}
+
+// route splits the path after the prefix into the ID and the action.
+func route(path, prefix string) (id string, action string) {
+	parts := strings.SplitN(strings.TrimPrefix(path, prefix), "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+
+	return parts[0], ""
+}
+
+func (h *handler) accounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var request registerRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	var account *types.Account
+	var err error
+	if request.Currency == "" {
+		account, err = h.service.RegisterAccount(request.Phone)
+	} else {
+		account, err = h.service.OpenAccount(request.Phone, request.Currency)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, accountOf(account))
+}
+
+func (h *handler) account(w http.ResponseWriter, r *http.Request) {
+	rawID, action := route(r.URL.Path, "/accounts/")
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: invalid account id %q", errBadRequest, rawID))
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.findAccount(w, id)
+	case action == "deposit" && r.Method == http.MethodPost:
+		h.deposit(w, r, id)
+	case action == "payments" && r.Method == http.MethodPost:
+		h.pay(w, r, id)
+	case action == "payments" && r.Method == http.MethodGet:
+		h.listPayments(w, id)
+	case action == "" || action == "payments":
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	case action == "deposit":
+		methodNotAllowed(w, http.MethodPost)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *handler) findAccount(w http.ResponseWriter, id int64) {
+	account, err := h.service.FindAccountByID(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, accountOf(account))
+}
+
+func (h *handler) deposit(w http.ResponseWriter, r *http.Request, id int64) {
+	var request depositRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	err := h.service.Deposit(id, request.Amount, payOptions(r, request.Currency)...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.findAccount(w, id)
+}
+
+func (h *handler) pay(w http.ResponseWriter, r *http.Request, id int64) {
+	var request payRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	payment, err := h.service.Pay(id, request.Amount, request.Category, payOptions(r, request.Currency)...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, paymentOf(payment))
+}
+
+func (h *handler) listPayments(w http.ResponseWriter, id int64) {
+	payments, err := h.service.AccountPayments(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result := make([]paymentJSON, len(payments))
+	for i := range payments {
+		result[i] = paymentOf(&payments[i])
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *handler) payment(w http.ResponseWriter, r *http.Request) {
+	id, action := route(r.URL.Path, "/payments/")
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.findPayment(w, id, http.StatusOK)
+	case action == "reject" && r.Method == http.MethodPost:
+		err := h.service.Reject(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		h.findPayment(w, id, http.StatusOK)
+	case action == "repeat" && r.Method == http.MethodPost:
+		payment, err := h.service.Repeat(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, paymentOf(payment))
+	case action == "favorite" && r.Method == http.MethodPost:
+		h.favoritePayment(w, r, id)
+	case action == "":
+		methodNotAllowed(w, http.MethodGet)
+	case action == "reject" || action == "repeat" || action == "favorite":
+		methodNotAllowed(w, http.MethodPost)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *handler) findPayment(w http.ResponseWriter, id string, status int) {
+	payment, err := h.service.FindPaymetByID(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, status, paymentOf(payment))
+}
+
+func (h *handler) favoritePayment(w http.ResponseWriter, r *http.Request, id string) {
+	var request favoriteRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	favorite, err := h.service.FavoritePayment(id, request.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, favoriteOf(favorite))
+}
+
+func (h *handler) favorite(w http.ResponseWriter, r *http.Request) {
+	id, action := route(r.URL.Path, "/favorites/")
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		favorite, err := h.service.FindFavoriteByID(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, favoriteOf(favorite))
+	case action == "pay" && r.Method == http.MethodPost:
+		payment, err := h.service.PayFromFavorite(id, payOptions(r, "")...)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, paymentOf(payment))
+	case action == "":
+		methodNotAllowed(w, http.MethodGet)
+	case action == "pay":
+		methodNotAllowed(w, http.MethodPost)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func payOptions(r *http.Request, currency types.Currency) []wallet.PayOption {
+	return wallet.PayOptions(currency, r.Header.Get("Idempotency-Key"))
+}
+
+func decode(w http.ResponseWriter, r *http.Request, request interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(request)
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: %v", errBadRequest, err))
+		return false
+	}
+
+	return true
+}
+
+func methodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, errorJSON{Code: "method_not_allowed", Error: "method not allowed"})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, code := statusOf(err)
+	if status == http.StatusInternalServerError {
+		log.Print(err)
+	}
+
+	writeJSON(w, status, errorJSON{Code: code, Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,189 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/wallet"
+)
+
+type testServer struct {
+	t       *testing.T
+	handler http.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	return &testServer{t: t, handler: NewHandler(wallet.NewService(wallet.NewMemoryRepository()))}
+}
+
+// do sends the request and decodes the response into result when it is
+// not nil.
+func (s *testServer) do(method, path, body string, headers map[string]string, result interface{}) int {
+	request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.handler.ServeHTTP(recorder, request)
+
+	if result != nil {
+		err := json.Unmarshal(recorder.Body.Bytes(), result)
+		if err != nil {
+			s.t.Errorf("%s %s: invalid response %q: %v", method, path, recorder.Body.String(), err)
+		}
+	}
+
+	return recorder.Code
+}
+
+func (s *testServer) addAccount(balance int) accountJSON {
+	var account accountJSON
+	status := s.do(http.MethodPost, "/accounts", `{"phone": "+992000000001"}`, nil, &account)
+	if status != http.StatusCreated {
+		s.t.Fatalf("register: invalid status %v", status)
+	}
+
+	status = s.do(http.MethodPost, "/accounts/1/deposit", `{"amount": `+jsonOf(balance)+`}`, nil, &account)
+	if status != http.StatusOK {
+		s.t.Fatalf("deposit: invalid status %v", status)
+	}
+
+	return account
+}
+
+func jsonOf(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func TestHandler_success(t *testing.T) {
+	s := newTestServer(t)
+	account := s.addAccount(1_000)
+	if account.ID != 1 || account.Balance != 1_000 || account.Phone != "+992000000001" {
+		t.Errorf("invalid account: %v", account)
+		return
+	}
+
+	var payment paymentJSON
+	status := s.do(http.MethodPost, "/accounts/1/payments", `{"amount": 100, "category": "auto"}`, nil, &payment)
+	if status != http.StatusCreated || payment.Amount != 100 || payment.AccountID != 1 {
+		t.Errorf("pay: invalid response %v: %v", status, payment)
+		return
+	}
+
+	var repeated paymentJSON
+	status = s.do(http.MethodPost, "/payments/"+payment.ID+"/repeat", "", nil, &repeated)
+	if status != http.StatusCreated || repeated.ID == payment.ID || repeated.Amount != 100 {
+		t.Errorf("repeat: invalid response %v: %v", status, repeated)
+		return
+	}
+
+	var rejected paymentJSON
+	status = s.do(http.MethodPost, "/payments/"+payment.ID+"/reject", "", nil, &rejected)
+	if status != http.StatusOK || rejected.Status != "FAIL" {
+		t.Errorf("reject: invalid response %v: %v", status, rejected)
+		return
+	}
+
+	var favorite favoriteJSON
+	status = s.do(http.MethodPost, "/payments/"+repeated.ID+"/favorite", `{"name": "car"}`, nil, &favorite)
+	if status != http.StatusCreated || favorite.Name != "car" || favorite.Amount != 100 {
+		t.Errorf("favorite: invalid response %v: %v", status, favorite)
+		return
+	}
+
+	status = s.do(http.MethodPost, "/favorites/"+favorite.ID+"/pay", "", nil, &payment)
+	if status != http.StatusCreated || payment.Category != "auto" {
+		t.Errorf("pay from favorite: invalid response %v: %v", status, payment)
+		return
+	}
+
+	var payments []paymentJSON
+	status = s.do(http.MethodGet, "/accounts/1/payments", "", nil, &payments)
+	if status != http.StatusOK || len(payments) != 3 {
+		t.Errorf("list payments: invalid response %v: %v", status, payments)
+		return
+	}
+
+	status = s.do(http.MethodGet, "/accounts/1", "", nil, &account)
+	if status != http.StatusOK || account.Balance != 800 {
+		t.Errorf("find account: invalid response %v: %v", status, account)
+	}
+}
+
+func TestHandler_idempotencyKey(t *testing.T) {
+	s := newTestServer(t)
+	s.addAccount(1_000)
+
+	headers := map[string]string{"Idempotency-Key": "order-1"}
+	var first, second paymentJSON
+	s.do(http.MethodPost, "/accounts/1/payments", `{"amount": 100, "category": "auto"}`, headers, &first)
+	s.do(http.MethodPost, "/accounts/1/payments", `{"amount": 100, "category": "auto"}`, headers, &second)
+	if first.ID == "" || first.ID != second.ID {
+		t.Errorf("payment not replayed: %v, %v", first, second)
+		return
+	}
+
+	var response errorJSON
+	status := s.do(http.MethodPost, "/accounts/1/payments", `{"amount": 200, "category": "auto"}`, headers, &response)
+	if status != http.StatusConflict || response.Code != "idempotency_key_reused" {
+		t.Errorf("invalid response %v: %v", status, response)
+	}
+}
+
+func TestHandler_fail(t *testing.T) {
+	s := newTestServer(t)
+	s.addAccount(1_000)
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{http.MethodGet, "/accounts/2", "", http.StatusNotFound, "account_not_found"},
+		{http.MethodGet, "/accounts/x", "", http.StatusBadRequest, "bad_request"},
+		{http.MethodGet, "/accounts/2/payments", "", http.StatusNotFound, "account_not_found"},
+		{http.MethodPost, "/accounts", `{"phone": "+992000000001"}`, http.StatusConflict, "phone_already_registered"},
+		{http.MethodPost, "/accounts", `{"phone": "12"}`, http.StatusUnprocessableEntity, "invalid_phone"},
+		{http.MethodPost, "/accounts", `{"phone": `, http.StatusBadRequest, "bad_request"},
+		{http.MethodPost, "/accounts", `{"phone": "+992000000002", "pin": 1}`, http.StatusBadRequest, "bad_request"},
+		{http.MethodPost, "/accounts/1/deposit", `{"amount": -1}`, http.StatusUnprocessableEntity, "invalid_amount"},
+		{http.MethodPost, "/accounts/1/payments", `{"amount": 2000, "category": "auto"}`, http.StatusUnprocessableEntity, "insufficient_balance"},
+		{http.MethodPost, "/accounts/1/payments", `{"amount": 1, "category": "auto", "currency": "XXX"}`, http.StatusUnprocessableEntity, "currency_mismatch"},
+		{http.MethodGet, "/payments/x", "", http.StatusNotFound, "payment_not_found"},
+		{http.MethodPost, "/payments/x/reject", "", http.StatusNotFound, "payment_not_found"},
+		{http.MethodPost, "/favorites/x/pay", "", http.StatusNotFound, "favorite_not_found"},
+		{http.MethodDelete, "/accounts/1", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodGet, "/accounts", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+
+	for _, test := range tests {
+		var response errorJSON
+		status := s.do(test.method, test.path, test.body, nil, &response)
+		if status != test.status || response.Code != test.code || response.Error == "" {
+			t.Errorf("%s %s: invalid response %v: %v", test.method, test.path, status, response)
+		}
+	}
+}
+
+func TestHandler_frozenAccount(t *testing.T) {
+	service := wallet.NewService(wallet.NewMemoryRepository())
+	s := &testServer{t: t, handler: NewHandler(service)}
+	s.addAccount(1_000)
+
+	err := service.Freeze(1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var response errorJSON
+	status := s.do(http.MethodPost, "/accounts/1/payments", `{"amount": 1, "category": "auto"}`, nil, &response)
+	if status != http.StatusForbidden || response.Code != "account_frozen" {
+		t.Errorf("invalid response %v: %v", status, response)
+	}
+}
@@ -0,0 +1,118 @@
+package httpapi
+
+import (
+	"time"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+// Amounts are in minor units of the currency.
+
+type registerRequest struct {
+	Phone    types.Phone    `json:"phone"`
+	Currency types.Currency `json:"currency,omitempty"`
+}
+
+type depositRequest struct {
+	Amount   types.Money    `json:"amount"`
+	Currency types.Currency `json:"currency,omitempty"`
+}
+
+type payRequest struct {
+	Amount   types.Money           `json:"amount"`
+	Category types.PaymentCategoty `json:"category"`
+	Currency types.Currency        `json:"currency,omitempty"`
+}
+
+type favoriteRequest struct {
+	Name string `json:"name"`
+}
+
+type errorJSON struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+type accountJSON struct {
+	ID        int64               `json:"id"`
+	Phone     types.Phone         `json:"phone"`
+	Balance   types.Money         `json:"balance"`
+	Currency  types.Currency      `json:"currency"`
+	Status    types.AccountStatus `json:"status"`
+	Overdraft types.Money         `json:"overdraft"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+type paymentJSON struct {
+	ID               string                `json:"id"`
+	AccountID        int64                 `json:"account_id"`
+	Amount           types.Money           `json:"amount"`
+	Currency         types.Currency        `json:"currency"`
+	Category         types.PaymentCategoty `json:"category"`
+	Status           types.PaymentStatus   `json:"status"`
+	Type             types.PaymentType     `json:"type"`
+	LinkedID         string                `json:"linked_id,omitempty"`
+	Refunded         types.Money           `json:"refunded"`
+	OriginalAmount   types.Money           `json:"original_amount,omitempty"`
+	OriginalCurrency types.Currency        `json:"original_currency,omitempty"`
+	Rate             types.Rate            `json:"rate,omitempty"`
+	CreatedAt        time.Time             `json:"created_at"`
+	UpdatedAt        time.Time             `json:"updated_at"`
+}
+
+type favoriteJSON struct {
+	ID        string                `json:"id"`
+	AccountID int64                 `json:"account_id"`
+	Name      string                `json:"name"`
+	Amount    types.Money           `json:"amount"`
+	Currency  types.Currency        `json:"currency"`
+	Category  types.PaymentCategoty `json:"category"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+func accountOf(account *types.Account) accountJSON {
+	return accountJSON{
+		ID:        account.ID,
+		Phone:     account.Phone,
+		Balance:   account.Balance,
+		Currency:  account.Currency,
+		Status:    account.Status,
+		Overdraft: account.Overdraft,
+		CreatedAt: account.CreatedAt,
+		UpdatedAt: account.UpdatedAt,
+	}
+}
+
+func paymentOf(payment *types.Payment) paymentJSON {
+	return paymentJSON{
+		ID:               payment.ID,
+		AccountID:        payment.AccountID,
+		Amount:           payment.Amount,
+		Currency:         payment.Currency,
+		Category:         payment.Category,
+		Status:           payment.Status,
+		Type:             payment.Type,
+		LinkedID:         payment.LinkedID,
+		Refunded:         payment.Refunded,
+		OriginalAmount:   payment.OriginalAmount,
+		OriginalCurrency: payment.OriginalCurrency,
+		Rate:             payment.Rate,
+		CreatedAt:        payment.CreatedAt,
+		UpdatedAt:        payment.UpdatedAt,
+	}
+}
+
+func favoriteOf(favorite *types.Favorite) favoriteJSON {
+	return favoriteJSON{
+		ID:        favorite.ID,
+		AccountID: favorite.AccountID,
+		Name:      favorite.Name,
+		Amount:    favorite.Amount,
+		Currency:  favorite.Currency,
+		Category:  favorite.Category,
+		CreatedAt: favorite.CreatedAt,
+		UpdatedAt: favorite.UpdatedAt,
+	}
+}
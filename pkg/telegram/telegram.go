@@ -0,0 +1,198 @@
+// Package telegram sends and receives messages through the Telegram Bot
+// API. A Client implements types.Messenger.
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+const DefaultBaseURL = "https://api.telegram.org"
+
+// APIError is returned when the Bot API refuses a request.
+type APIError struct {
+	Method      string
+	Code        int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram %s: %d %s", e.Method, e.Code, e.Description)
+}
+
+var _ types.Messenger = (*Client)(nil)
+
+type Client struct {
+	token   string
+	chatID  int64
+	baseURL string
+	http    *http.Client
+
+	mu      sync.Mutex
+	offset  int64
+	pending []string
+}
+
+type Option func(c *Client)
+
+// WithBaseURL points the client to another Bot API server, such as a
+// local fake in tests.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// NewClient returns a client of the bot with the token which talks to
+// the chat.
+func NewClient(token string, chatID int64, options ...Option) *Client {
+	c := &Client{
+		token:   token,
+		chatID:  chatID,
+		baseURL: DefaultBaseURL,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+type response struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+}
+
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+// SendMessage sends the text to the chat of the client.
+func (c *Client) SendMessage(text string) error {
+	return c.call("sendMessage", map[string]interface{}{
+		"chat_id": c.chatID,
+		"text":    text,
+	}, nil)
+}
+
+// Updates returns the updates of the bot received after the last call.
+func (c *Client) Updates() ([]Update, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var updates []Update
+	err := c.call("getUpdates", map[string]interface{}{"offset": c.offset}, &updates)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, update := range updates {
+		if update.UpdateID >= c.offset {
+			c.offset = update.UpdateID + 1
+		}
+	}
+
+	return updates, nil
+}
+
+func (c *Client) Send(message string) bool {
+	return c.SendMessage(message) == nil
+}
+
+// Receive returns the text of the next message in the chat of the
+// client. Messages fetched together are queued and returned one by one.
+func (c *Client) Receive() (message string, ok bool) {
+	if message, ok := c.nextPending(); ok {
+		return message, true
+	}
+
+	updates, err := c.Updates()
+	if err != nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	for _, update := range updates {
+		if update.Message != nil && update.Message.Chat.ID == c.chatID {
+			c.pending = append(c.pending, update.Message.Text)
+		}
+	}
+	c.mu.Unlock()
+
+	return c.nextPending()
+}
+
+func (c *Client) nextPending() (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.pending) == 0 {
+		return "", false
+	}
+
+	message := c.pending[0]
+	c.pending = c.pending[1:]
+
+	return message, true
+}
+
+func (c *Client) call(method string, params map[string]interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.baseURL + "/bot" + url.PathEscape(c.token) + "/" + method
+	resp, err := c.http.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var data response
+	err = json.NewDecoder(resp.Body).Decode(&data)
+	if err != nil {
+		return &APIError{Method: method, Code: resp.StatusCode, Description: "invalid response: " + err.Error()}
+	}
+
+	if !data.OK {
+		code := data.ErrorCode
+		if code == 0 {
+			code = resp.StatusCode
+		}
+		return &APIError{Method: method, Code: code, Description: data.Description}
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data.Result, result)
+}
@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeBot struct {
+	token    string
+	sent     []map[string]interface{}
+	updates  string
+	failures int
+}
+
+func (b *fakeBot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/bot" + b.token + "/sendMessage":
+		if b.failures > 0 {
+			b.failures--
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests"}`))
+			return
+		}
+
+		var params map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&params)
+		b.sent = append(b.sent, params)
+		w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	case "/bot" + b.token + "/getUpdates":
+		w.Write([]byte(`{"ok":true,"result":` + b.updates + `}`))
+		b.updates = "[]"
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"ok":false,"error_code":404,"description":"Not Found"}`))
+	}
+}
+
+func TestClient_SendMessage_success(t *testing.T) {
+	bot := &fakeBot{token: "123:abc"}
+	server := httptest.NewServer(bot)
+	defer server.Close()
+
+	client := NewClient("123:abc", 42, WithBaseURL(server.URL))
+	if !client.Send("hello") {
+		t.Error("message not sent")
+		return
+	}
+
+	if len(bot.sent) != 1 || bot.sent[0]["text"] != "hello" || bot.sent[0]["chat_id"] != float64(42) {
+		t.Errorf("invalid sent messages: %v", bot.sent)
+	}
+}
+
+func TestClient_SendMessage_fail(t *testing.T) {
+	bot := &fakeBot{token: "123:abc", failures: 1}
+	server := httptest.NewServer(bot)
+	defer server.Close()
+
+	client := NewClient("123:abc", 42, WithBaseURL(server.URL))
+	err := client.SendMessage("hello")
+	apiError, ok := err.(*APIError)
+	if !ok || apiError.Code != 429 || apiError.Method != "sendMessage" {
+		t.Errorf("invalid error: %v", err)
+		return
+	}
+
+	client = NewClient("wrong", 42, WithBaseURL(server.URL))
+	if client.Send("hello") {
+		t.Error("message sent with a wrong token")
+	}
+}
+
+func TestClient_Receive(t *testing.T) {
+	bot := &fakeBot{
+		token: "123:abc",
+		updates: `[
+			{"update_id":10,"message":{"message_id":1,"chat":{"id":7},"text":"other"}},
+			{"update_id":11,"message":{"message_id":2,"chat":{"id":42},"text":"balance"}},
+			{"update_id":12,"message":{"message_id":3,"chat":{"id":42},"text":"history"}}
+		]`,
+	}
+	server := httptest.NewServer(bot)
+	defer server.Close()
+
+	client := NewClient("123:abc", 42, WithBaseURL(server.URL))
+	message, ok := client.Receive()
+	if !ok || message != "balance" {
+		t.Errorf("invalid message: %q, %v", message, ok)
+		return
+	}
+
+	if client.offset != 13 {
+		t.Errorf("invalid offset: %v", client.offset)
+	}
+
+	message, ok = client.Receive()
+	if !ok || message != "history" {
+		t.Errorf("invalid second message: %q, %v", message, ok)
+		return
+	}
+
+	_, ok = client.Receive()
+	if ok {
+		t.Error("message received twice")
+	}
+}
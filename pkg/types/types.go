@@ -1,7 +1,48 @@
 package types
 
+import "time"
+
 type Money int64
 
+type Currency string
+
+const (
+	CurrencyTJS Currency = "TJS"
+	CurrencyUSD Currency = "USD"
+	CurrencyEUR Currency = "EUR"
+	CurrencyRUB Currency = "RUB"
+)
+
+const ErrCurrencyMismatch = Error("currencies do not match")
+
+// Rate is the price of one minor unit of a currency in minor units of
+// another currency, multiplied by RateScale.
+type Rate int64
+
+const RateScale Rate = 1_000_000
+
+// Cash is an amount of money in minor units of the currency.
+type Cash struct {
+	Amount   Money
+	Currency Currency
+}
+
+func (c Cash) Add(other Cash) (Cash, error) {
+	if c.Currency != other.Currency {
+		return Cash{}, ErrCurrencyMismatch
+	}
+
+	return Cash{Amount: c.Amount + other.Amount, Currency: c.Currency}, nil
+}
+
+func (c Cash) Sub(other Cash) (Cash, error) {
+	if c.Currency != other.Currency {
+		return Cash{}, ErrCurrencyMismatch
+	}
+
+	return Cash{Amount: c.Amount - other.Amount, Currency: c.Currency}, nil
+}
+
 type PaymentCategoty string
 
 type PaymentStatus string
@@ -10,6 +51,17 @@ const (
 	PaymentStatusOk         PaymentStatus = "OK"
 	PaymentStatusFail       PaymentStatus = "FAIL"
 	PaymentStatusInProgress PaymentStatus = "INPROGRESS"
+
+	PaymentStatusRefunded          PaymentStatus = "REFUNDED"
+	PaymentStatusPartiallyRefunded PaymentStatus = "PARTIALLY_REFUNDED"
+)
+
+type PaymentType string
+
+const (
+	PaymentTypePayment     PaymentType = "PAYMENT"
+	PaymentTypeTransferOut PaymentType = "TRANSFER_OUT"
+	PaymentTypeTransferIn  PaymentType = "TRANSFER_IN"
 )
 
 type Payment struct {
@@ -18,14 +70,47 @@ type Payment struct {
 	Amount    Money
 	Category  PaymentCategoty
 	Status    PaymentStatus
+	Type      PaymentType
+	LinkedID  string
+	Refunded  Money
+	Currency  Currency
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	OriginalAmount   Money
+	OriginalCurrency Currency
+	Rate             Rate
 }
 
 type Phone string
 
 type Account struct {
-	ID      int64
-	Phone   Phone
-	Balance Money
+	ID        int64
+	Phone     Phone
+	Balance   Money
+	Currency  Currency
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Limits    Limits
+	Overdraft Money
+	Status    AccountStatus
+}
+
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "ACTIVE"
+	AccountStatusFrozen AccountStatus = "FROZEN"
+	AccountStatusClosed AccountStatus = "CLOSED"
+)
+
+// Limits caps spending of an account in its currency. A zero value means
+// there is no limit.
+type Limits struct {
+	Single     Money
+	Daily      Money
+	Monthly    Money
+	Categories map[PaymentCategoty]Money
 }
 
 type Messenger interface {
@@ -60,9 +145,32 @@ type Favorite struct {
 	Name      string
 	Amount    Money
 	Category  PaymentCategoty
+	Currency  Currency
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Posting struct {
+	ID        string
+	Debit     int64
+	Credit    int64
+	Amount    Money
+	Currency  Currency
+	PaymentID string
+	CreatedAt time.Time
+}
+
+// IdempotencyKey remembers a money-moving call made with the key, so a
+// retry of the call returns the result of the first one.
+type IdempotencyKey struct {
+	Key         string
+	Fingerprint string
+	PaymentID   string
+	CreatedAt   time.Time
 }
 
 type Progress struct {
-	Part   int
-	Result Money
+	Part     int
+	Result   Money
+	Currency Currency
 }
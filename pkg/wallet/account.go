@@ -0,0 +1,196 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAccountFrozen   = errors.New("account is frozen")
+	ErrAccountClosed   = errors.New("account is closed")
+	ErrAccountNotEmpty = errors.New("account balance is not zero")
+)
+
+// PayoutCategory is the category of the payment which pays out the
+// balance of a closed account.
+const PayoutCategory types.PaymentCategoty = "payout"
+
+// AccountStatusError is returned when an operation is refused because of
+// the status of the account. It matches ErrAccountFrozen or
+// ErrAccountClosed with errors.Is.
+type AccountStatusError struct {
+	AccountID int64
+	Status    types.AccountStatus
+	Operation string
+}
+
+func (e *AccountStatusError) Error() string {
+	return fmt.Sprintf("account %d is %s: can't %s", e.AccountID, strings.ToLower(string(e.Status)), e.Operation)
+}
+
+func (e *AccountStatusError) Is(target error) bool {
+	switch e.Status {
+	case types.AccountStatusFrozen:
+		return target == ErrAccountFrozen
+	case types.AccountStatusClosed:
+		return target == ErrAccountClosed
+	}
+
+	return false
+}
+
+// checkActive refuses the operation on an account which is not active.
+// Accounts saved without a status are active.
+func checkActive(account *types.Account, operation string) error {
+	if account.Status == "" || account.Status == types.AccountStatusActive {
+		return nil
+	}
+
+	return &AccountStatusError{AccountID: account.ID, Status: account.Status, Operation: operation}
+}
+
+// Freeze blocks all money movement on the account until Unfreeze.
+func (s *Service) Freeze(accountID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, err := s.repo().FindAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	switch account.Status {
+	case types.AccountStatusFrozen:
+		return nil
+	case types.AccountStatusClosed:
+		return &AccountStatusError{AccountID: account.ID, Status: account.Status, Operation: "freeze"}
+	}
+
+	from := account.Status
+	account.Status = types.AccountStatusFrozen
+
+	return s.save(&Batch{
+		Accounts: []*types.Account{account},
+		Events:   []Event{statusChanged(account, from)},
+	})
+}
+
+func (s *Service) Unfreeze(accountID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, err := s.repo().FindAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	if account.Status == types.AccountStatusClosed {
+		return &AccountStatusError{AccountID: account.ID, Status: account.Status, Operation: "unfreeze"}
+	}
+
+	if account.Status != types.AccountStatusFrozen {
+		return nil
+	}
+
+	from := account.Status
+	account.Status = types.AccountStatusActive
+
+	return s.save(&Batch{
+		Accounts: []*types.Account{account},
+		Events:   []Event{statusChanged(account, from)},
+	})
+}
+
+// Close closes the active account with a zero balance. A closed account
+// can't be used anymore.
+func (s *Service) Close(accountID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, err := s.findClosable(accountID)
+	if err != nil {
+		return err
+	}
+
+	if account.Balance != 0 {
+		return fmt.Errorf("account %d: %w: %d", account.ID, ErrAccountNotEmpty, account.Balance)
+	}
+
+	from := account.Status
+	account.Status = types.AccountStatusClosed
+
+	return s.save(&Batch{
+		Accounts: []*types.Account{account},
+		Events:   []Event{statusChanged(account, from)},
+	})
+}
+
+// CloseWithPayout pays out the whole balance of the active account and
+// closes it. The payout is returned, or nil when the balance was zero.
+func (s *Service) CloseWithPayout(accountID int64) (*types.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, err := s.findClosable(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.Balance < 0 {
+		return nil, fmt.Errorf("account %d: %w: %d", account.ID, ErrAccountNotEmpty, account.Balance)
+	}
+
+	batch := &Batch{Accounts: []*types.Account{account}}
+
+	var payment *types.Payment
+	if account.Balance > 0 {
+		cash := balanceOf(account)
+		payment = &types.Payment{
+			ID:        uuid.New().String(),
+			AccountID: account.ID,
+			Amount:    cash.Amount,
+			Category:  PayoutCategory,
+			Status:    types.PaymentStatusOk,
+			Type:      types.PaymentTypePayment,
+			Currency:  cash.Currency,
+		}
+
+		err = debit(account, cash)
+		if err != nil {
+			return nil, err
+		}
+
+		batch.Payments = []*types.Payment{payment}
+		batch.Postings = []*types.Posting{s.newPosting(account.ID, ExternalAccountID, cash, payment.ID)}
+		batch.Events = []Event{paymentCreated(payment, account)}
+	}
+
+	from := account.Status
+	account.Status = types.AccountStatusClosed
+	batch.Events = append(batch.Events, statusChanged(account, from))
+
+	err = s.save(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+func (s *Service) findClosable(accountID int64) (*types.Account, error) {
+	account, err := s.repo().FindAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkActive(account, "close")
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
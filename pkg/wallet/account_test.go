@@ -0,0 +1,250 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func TestService_Freeze_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	favorite, err := s.FavoritePayment(payments[0].ID, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Freeze(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkFrozen := func(err error, operation string) {
+		t.Helper()
+		var statusError *AccountStatusError
+		if !errors.As(err, &statusError) || !errors.Is(err, ErrAccountFrozen) {
+			t.Errorf("invalid error: %v", err)
+			return
+		}
+
+		if statusError.AccountID != account.ID || statusError.Operation != operation {
+			t.Errorf("invalid status error: %v", statusError)
+		}
+	}
+
+	checkFrozen(s.Deposit(account.ID, 100), "deposit")
+
+	_, err = s.Pay(account.ID, 100, "auto")
+	checkFrozen(err, "pay")
+
+	_, err = s.Repeat(payments[0].ID)
+	checkFrozen(err, "pay")
+
+	_, err = s.PayFromFavorite(favorite.ID)
+	checkFrozen(err, "pay")
+
+	checkFrozen(s.Reject(payments[0].ID), "reject")
+	checkFrozen(s.Refund(payments[0].ID, 100), "refund")
+
+	if s.balance(account.ID) != 9_000_00 {
+		t.Errorf("invalid balance: %v", s.balance(account.ID))
+	}
+
+	err = s.Unfreeze(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(payments[0].ID)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestService_Transfer_frozen(t *testing.T) {
+	s := newTestService()
+	from, to, err := s.addTransferAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Freeze(to.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Transfer(from.ID, to.ID, 100)
+	if !errors.Is(err, ErrAccountFrozen) {
+		t.Error(err)
+	}
+}
+
+func TestService_Close_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Close(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Deposit(account.ID, 100)
+	if !errors.Is(err, ErrAccountClosed) {
+		t.Error(err)
+		return
+	}
+
+	err = s.Unfreeze(account.ID)
+	if !errors.Is(err, ErrAccountClosed) {
+		t.Error(err)
+		return
+	}
+
+	err = s.Close(account.ID)
+	if !errors.Is(err, ErrAccountClosed) {
+		t.Error(err)
+	}
+}
+
+func TestService_Close_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992000000001", 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Close(account.ID)
+	if !errors.Is(err, ErrAccountNotEmpty) {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetOverdraft(account.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(account.ID, 150, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.CloseWithPayout(account.ID)
+	if !errors.Is(err, ErrAccountNotEmpty) {
+		t.Error(err)
+	}
+}
+
+func TestService_CloseWithPayout_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992000000001", 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Freeze(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.CloseWithPayout(account.ID)
+	var statusError *AccountStatusError
+	if !errors.As(err, &statusError) || !errors.Is(err, ErrAccountFrozen) || statusError.Operation != "close" {
+		t.Errorf("payout from a frozen account: %v", err)
+		return
+	}
+
+	err = s.Unfreeze(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payout, err := s.CloseWithPayout(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if payout.Amount != 100 || payout.Category != PayoutCategory {
+		t.Errorf("invalid payout: %v", payout)
+	}
+
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got.Balance != 0 || got.Status != types.AccountStatusClosed {
+		t.Errorf("invalid account: %v", got)
+	}
+
+	mismatches, err := s.Reconcile()
+	if err != nil || len(mismatches) != 0 {
+		t.Errorf("ledger does not match balances: %v, %v", mismatches, err)
+	}
+}
+
+func TestService_Import_accountStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Freeze(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = imported.Deposit(account.ID, 100)
+	if !errors.Is(err, ErrAccountFrozen) {
+		t.Error(err)
+	}
+}
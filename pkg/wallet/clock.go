@@ -0,0 +1,23 @@
+package wallet
+
+import "time"
+
+// Clock tells the Service the current time. Tests pass a fixed clock to
+// get deterministic timestamps.
+type Clock interface {
+	Now() time.Time
+}
+
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+func (s *Service) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+
+	return s.clock.Now()
+}
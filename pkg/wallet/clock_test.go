@@ -0,0 +1,183 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2020, 12, 29, 10, 0, 0, 0, time.UTC)}
+}
+
+func TestService_timestamps(t *testing.T) {
+	clock := newTestClock()
+	s := NewService(NewMemoryRepository(), WithClock(clock))
+	created := clock.now
+
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	clock.add(time.Hour)
+	err = s.Deposit(account.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	clock.add(time.Hour)
+	payment, err := s.Pay(account.ID, 10, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	clock.add(time.Hour)
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	savedAccount, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !savedAccount.CreatedAt.Equal(created) || !savedAccount.UpdatedAt.Equal(clock.now) {
+		t.Errorf("invalid account timestamps: %v", savedAccount)
+		return
+	}
+
+	savedPayment, err := s.FindPaymetByID(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !savedPayment.CreatedAt.Equal(created.Add(2*time.Hour)) || !savedPayment.UpdatedAt.Equal(clock.now) {
+		t.Errorf("invalid payment timestamps: %v", savedPayment)
+	}
+}
+
+func TestService_Export_timestamps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	clock := newTestClock()
+	s := newTestService()
+	s.clock = clock
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	clock.add(time.Minute)
+	favorite, err := s.FavoritePayment(payments[0].ID, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	err = imported.actionByAccounts(filepath.Join(dir, "accounts.dump"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = imported.actionByPayments(filepath.Join(dir, "payments.dump"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = imported.actionByFavorites(filepath.Join(dir, "favorites.dump"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	savedPayment, err := imported.FindPaymetByID(payments[0].ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !savedPayment.CreatedAt.Equal(payments[0].CreatedAt) || !savedPayment.UpdatedAt.Equal(payments[0].UpdatedAt) {
+		t.Errorf("invalid payment timestamps: got %v, want %v", savedPayment, payments[0])
+		return
+	}
+
+	savedFavorite, err := imported.FindFavoriteByID(favorite.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !savedFavorite.CreatedAt.Equal(clock.now) {
+		t.Errorf("invalid favorite timestamps: %v", savedFavorite)
+	}
+}
+
+func TestService_actionByPayments_oldFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "payments.dump")
+	err = ioutil.WriteFile(path, []byte("1;1;100;auto;INPROGRESS\n"), 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s := newTestService()
+	err = s.actionByPayments(path)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment, err := s.FindPaymetByID("1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if payment.Amount != 100 || !payment.CreatedAt.IsZero() {
+		t.Errorf("invalid payment: %v", payment)
+	}
+}
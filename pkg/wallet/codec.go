@@ -0,0 +1,242 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var ErrInvalidDump = errors.New("invalid dump")
+
+// ParseError is returned for a record of a dump which can't be parsed.
+// Line counts records from 1. It matches ErrInvalidDump with errors.Is.
+type ParseError struct {
+	File  string
+	Line  int
+	Field string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+	}
+
+	return fmt.Sprintf("%s:%d: %s: %v", e.File, e.Line, e.Field, e.Err)
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == ErrInvalidDump
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	fieldSeparator  = ';'
+	recordSeparator = '\n'
+	escapeChar      = '\\'
+)
+
+// Field counts of the records of every version of the dumps. Later
+// versions append fields to the end of the record.
+var (
+	accountFields  = []int{3, 5, 6, 10, 11, 12}
+	paymentFields  = []int{5, 7, 8, 10, 11, 14}
+	favoriteFields = []int{5, 7, 8}
+	keyFields      = []int{4}
+)
+
+// escape escapes the escape char, the specials and line breaks, so the
+// value can be joined with the specials as separators.
+func escape(value string, specials string) string {
+	if !strings.ContainsAny(value, specials+"\\\n\r") {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == escapeChar || strings.IndexByte(specials, c) >= 0:
+			b.WriteByte(escapeChar)
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+func unescape(value string) (string, error) {
+	if strings.IndexByte(value, escapeChar) < 0 {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != escapeChar {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i == len(value) {
+			return "", errors.New("escape at the end of the field")
+		}
+
+		switch c := value[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case escapeChar, ';', '|', ',', '=':
+			b.WriteByte(c)
+		default:
+			return "", fmt.Errorf("invalid escape %q", value[i-1:i+1])
+		}
+	}
+
+	return b.String(), nil
+}
+
+// splitEscaped splits the value at the separators which are not escaped.
+// The parts are left escaped.
+func splitEscaped(value string, separator byte) []string {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case escapeChar:
+			i++
+		case separator:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, value[start:])
+}
+
+// formatRecord escapes the fields and joins them into a record ended with
+// the terminator.
+func formatRecord(terminator byte, fields ...string) string {
+	var b strings.Builder
+	for i, field := range fields {
+		if i != 0 {
+			b.WriteByte(fieldSeparator)
+		}
+		b.WriteString(escape(field, ";|"))
+	}
+	b.WriteByte(terminator)
+
+	return b.String()
+}
+
+func formatLine(fields ...string) string {
+	return formatRecord(recordSeparator, fields...)
+}
+
+// dumpReader reads the records of a dump one by one.
+type dumpReader struct {
+	file    string
+	records []string
+	counts  []int
+	line    int
+	fields  []string
+}
+
+// newDumpReader reads the records of data separated by the separator,
+// which must have one of the counts of fields.
+func newDumpReader(file string, data string, separator byte, counts []int) *dumpReader {
+	return &dumpReader{
+		file:    file,
+		records: splitEscaped(data, separator),
+		counts:  counts,
+	}
+}
+
+// next moves to the next record, skipping empty ones. It returns false
+// when there are no more records.
+func (r *dumpReader) next() (bool, error) {
+	for r.line < len(r.records) {
+		record := r.records[r.line]
+		r.line++
+		if record == "" {
+			continue
+		}
+
+		r.fields = splitEscaped(record, fieldSeparator)
+		if !containsCount(r.counts, len(r.fields)) {
+			return false, r.err("", fmt.Errorf("%d fields, want one of %v", len(r.fields), r.counts))
+		}
+
+		for i, field := range r.fields {
+			value, err := unescape(field)
+			if err != nil {
+				return false, r.err(fmt.Sprintf("field %d", i+1), err)
+			}
+			r.fields[i] = value
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// has tells whether the record has the field at the index.
+func (r *dumpReader) has(index int) bool {
+	return index < len(r.fields)
+}
+
+func (r *dumpReader) field(index int) string {
+	return r.fields[index]
+}
+
+func (r *dumpReader) int(index int, name string) (int, error) {
+	value, err := strconv.Atoi(r.fields[index])
+	if err != nil {
+		return 0, r.err(name, err)
+	}
+
+	return value, nil
+}
+
+func (r *dumpReader) money(index int, name string) (types.Money, error) {
+	value, err := r.int(index, name)
+	return types.Money(value), err
+}
+
+func (r *dumpReader) time(index int, name string) (time.Time, error) {
+	value, err := parseTime(r.fields[index])
+	if err != nil {
+		return time.Time{}, r.err(name, err)
+	}
+
+	return value, nil
+}
+
+func (r *dumpReader) err(field string, err error) error {
+	return &ParseError{File: r.file, Line: r.line, Field: field, Err: err}
+}
+
+func containsCount(counts []int, count int) bool {
+	for _, c := range counts {
+		if c == count {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,169 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func TestFormatRecord_roundTrip(t *testing.T) {
+	fields := []string{"plain", "semi;colon", "pipe|line", "multi\nline\r", `back\slash`, ""}
+	data := formatLine(fields...) + formatLine("second", "", "", "", "", "")
+
+	r := newDumpReader("test.dump", data, recordSeparator, []int{6})
+	for _, want := range [][]string{fields, {"second", "", "", "", "", ""}} {
+		ok, err := r.next()
+		if err != nil || !ok {
+			t.Errorf("record not read: %v", err)
+			return
+		}
+
+		if !reflect.DeepEqual(r.fields, want) {
+			t.Errorf("invalid fields: got - %q, want - %q", r.fields, want)
+		}
+	}
+
+	ok, err := r.next()
+	if err != nil || ok {
+		t.Errorf("invalid end of dump: %v, %v", ok, err)
+	}
+}
+
+func TestDumpReader_errors(t *testing.T) {
+	tests := []struct {
+		data  string
+		line  int
+		field string
+	}{
+		{"1;x;2\n", 1, ""},
+		{"1;2;3;4;5;6\n1;2;3;4\n", 2, ""},
+		{"1;2;3;4;5;6\n\n1;2;3;4;5;x\\\n", 3, "field 6"},
+	}
+
+	for _, test := range tests {
+		r := newDumpReader("test.dump", test.data, recordSeparator, []int{6})
+		var err error
+		for ok := true; ok && err == nil; {
+			ok, err = r.next()
+		}
+
+		var parseError *ParseError
+		if !errors.As(err, &parseError) || !errors.Is(err, ErrInvalidDump) {
+			t.Errorf("%q: invalid error: %v", test.data, err)
+			continue
+		}
+
+		if parseError.File != "test.dump" || parseError.Line != test.line || parseError.Field != test.field {
+			t.Errorf("%q: invalid error: %+v", test.data, parseError)
+		}
+	}
+}
+
+func TestService_Import_escapedFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992000000001", 1_000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	category := types.PaymentCategoty("car;taxi|bus=1,2")
+	limits := types.Limits{Categories: map[types.PaymentCategoty]types.Money{category: 500}}
+	err = s.SetLimits(account.ID, limits)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment, err := s.Pay(account.ID, 100, category)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	favorite, err := s.FavoritePayment(payment.ID, "home\nwork; \\daily|")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	gotAccount, err := imported.FindAccountByID(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !reflect.DeepEqual(gotAccount.Limits, limits) {
+		t.Errorf("invalid limits: got - %v, want - %v", gotAccount.Limits, limits)
+	}
+
+	gotPayment, err := imported.FindPaymetByID(payment.ID)
+	if err != nil || gotPayment.Category != category {
+		t.Errorf("invalid payment: %v, %v", gotPayment, err)
+	}
+
+	gotFavorite, err := imported.FindFavoriteByID(favorite.ID)
+	if err != nil || gotFavorite.Name != favorite.Name {
+		t.Errorf("invalid favorite: %v, %v", gotFavorite, err)
+	}
+}
+
+func TestService_Import_shortRecord(t *testing.T) {
+	_, dir, cleanup := exportTestService(t)
+	defer cleanup()
+
+	rewriteDump(t, dir, paymentsFile, "p1;1;100\n")
+
+	err := newTestService().Import(dir)
+	var parseError *ParseError
+	if !errors.As(err, &parseError) || parseError.File != paymentsFile || parseError.Line != 1 {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestService_ImportFromFile_invalidRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "export.txt")
+	err = ioutil.WriteFile(path, []byte("1;+992000000001;100|2;+992000000002|"), 0644)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = newTestService().ImportFromFile(path)
+	var parseError *ParseError
+	if !errors.As(err, &parseError) || parseError.Line != 2 {
+		t.Errorf("invalid error: %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var (
+	ErrUnknownCollection = errors.New("unknown collection")
+	ErrInvalidColumn     = errors.New("invalid column")
+)
+
+// Collection names a collection of the Service which is exported to CSV.
+type Collection string
+
+const (
+	CollectionAccounts  Collection = "accounts"
+	CollectionPayments  Collection = "payments"
+	CollectionFavorites Collection = "favorites"
+)
+
+// csvColumns are the columns of the collections in the order of the
+// fields of their dump records.
+var csvColumns = map[Collection][]string{
+	CollectionAccounts: {
+		"id", "phone", "balance", "created_at", "updated_at", "currency",
+		"single_limit", "daily_limit", "monthly_limit", "category_limits",
+		"overdraft", "status",
+	},
+	CollectionPayments: {
+		"id", "account_id", "amount", "category", "status", "type", "linked_id",
+		"refunded", "created_at", "updated_at", "currency",
+		"original_amount", "original_currency", "rate",
+	},
+	CollectionFavorites: {
+		"id", "account_id", "name", "amount", "category", "created_at",
+		"updated_at", "currency",
+	},
+}
+
+// CSVOption changes a CSV export of the Service.
+type CSVOption func(o *csvOptions)
+
+type csvOptions struct {
+	columns []string
+}
+
+// WithColumns exports only the columns, in the given order. By default
+// all columns are exported, and only such exports can be imported back.
+func WithColumns(columns ...string) CSVOption {
+	return func(o *csvOptions) {
+		o.columns = columns
+	}
+}
+
+// ExportCSV writes the collection to w as RFC 4180 CSV with a header row.
+// The records are read at once and every row is written as soon as it is
+// formatted. An export of all columns is read back by ImportCSV.
+func (s *Service) ExportCSV(w io.Writer, collection Collection, options ...CSVOption) error {
+	all, ok := csvColumns[collection]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCollection, collection)
+	}
+
+	o := &csvOptions{columns: all}
+	for _, option := range options {
+		option(o)
+	}
+
+	indexes, err := columnIndexes(all, o.columns)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	err = writer.Write(o.columns)
+	if err != nil {
+		return err
+	}
+
+	row := make([]string, len(indexes))
+	write := func(fields []string) error {
+		for i, index := range indexes {
+			row[i] = fields[index]
+		}
+
+		return writer.Write(row)
+	}
+
+	switch collection {
+	case CollectionAccounts:
+		accounts, err := s.snapshotAccounts()
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			err = write(accountRecord(account))
+			if err != nil {
+				return err
+			}
+		}
+	case CollectionPayments:
+		payments, err := s.snapshotPayments()
+		if err != nil {
+			return err
+		}
+
+		for _, payment := range payments {
+			err = write(paymentRecord(payment))
+			if err != nil {
+				return err
+			}
+		}
+	case CollectionFavorites:
+		favorites, err := s.snapshotFavorites()
+		if err != nil {
+			return err
+		}
+
+		for _, favorite := range favorites {
+			err = write(favoriteRecord(favorite))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func (s *Service) snapshotAccounts() ([]types.Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	accounts, err := s.repo().Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	// the limits are formatted after the lock is released
+	for i := range accounts {
+		accounts[i].Limits = copyLimits(accounts[i].Limits)
+	}
+
+	return accounts, nil
+}
+
+func (s *Service) snapshotPayments() ([]types.Payment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.repo().Payments()
+}
+
+func (s *Service) snapshotFavorites() ([]types.Favorite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.repo().Favorites()
+}
+
+// columnIndexes returns the indexes of the columns among all columns of a
+// collection.
+func columnIndexes(all []string, columns []string) ([]int, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%w: no columns", ErrInvalidColumn)
+	}
+
+	indexes := make([]int, len(columns))
+	seen := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		index := -1
+		for j, name := range all {
+			if name == column {
+				index = j
+			}
+		}
+
+		if index < 0 || seen[column] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidColumn, column)
+		}
+
+		seen[column] = true
+		indexes[i] = index
+	}
+
+	return indexes, nil
+}
+
+// ImportCSV reads the collection from r as written by ExportCSV with all
+// columns, in any order. The records are applied together, or not at all
+// when any of them is invalid.
+func (s *Service) ImportCSV(r io.Reader, collection Collection) error {
+	all, ok := csvColumns[collection]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCollection, collection)
+	}
+
+	var parse func(s *Service, r *dumpReader, batch *Batch) error
+	switch collection {
+	case CollectionAccounts:
+		parse = (*Service).parseAccount
+	case CollectionPayments:
+		parse = (*Service).parsePayment
+	case CollectionFavorites:
+		parse = (*Service).parseFavorite
+	}
+
+	file := string(collection) + ".csv"
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return csvError(file, err)
+	}
+
+	indexes, err := columnIndexes(all, header)
+	if err != nil {
+		return &ParseError{File: file, Line: 1, Err: err}
+	}
+
+	if len(indexes) != len(all) {
+		for _, column := range all {
+			if !containsColumn(header, column) {
+				return &ParseError{File: file, Line: 1, Field: column, Err: errors.New("missing column")}
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := &Batch{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return csvError(file, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		fields := make([]string, len(all))
+		for i, index := range indexes {
+			fields[index] = row[i]
+		}
+
+		err = parse(s, &dumpReader{file: file, line: line, fields: fields}, batch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.saveImported(batch)
+}
+
+func csvError(file string, err error) error {
+	var parseError *csv.ParseError
+	if errors.As(err, &parseError) {
+		return &ParseError{File: file, Line: parseError.Line, Err: parseError.Err}
+	}
+
+	return err
+}
+
+func containsColumn(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
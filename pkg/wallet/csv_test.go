@@ -0,0 +1,123 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func TestService_ExportCSV_roundTrip(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetLimits(account.ID, types.Limits{Categories: map[types.PaymentCategoty]types.Money{"a,b=c": 100}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.FavoritePayment(payments[0].ID, "say \"hi\",\nthen pay")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	for _, collection := range []Collection{CollectionAccounts, CollectionPayments, CollectionFavorites} {
+		buf := &bytes.Buffer{}
+		err = s.ExportCSV(buf, collection)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		err = imported.ImportCSV(buf, collection)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	for _, collection := range []Collection{CollectionAccounts, CollectionPayments, CollectionFavorites} {
+		want, got := &bytes.Buffer{}, &bytes.Buffer{}
+		err = s.ExportCSV(want, collection)
+		if err == nil {
+			err = imported.ExportCSV(got, collection)
+		}
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if got.String() != want.String() {
+			t.Errorf("%s: invalid records:\ngot  - %q\nwant - %q", collection, got.String(), want.String())
+		}
+	}
+}
+
+func TestService_ExportCSV_columns(t *testing.T) {
+	s := newTestService()
+	_, err := s.addAccountWithBalance("+992000000001", 1_000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	err = s.ExportCSV(buf, CollectionAccounts, WithColumns("phone", "balance", "id"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := "phone,balance,id\n+992000000001,1000,1\n"
+	if buf.String() != want {
+		t.Errorf("invalid export: got - %q, want - %q", buf.String(), want)
+	}
+
+	err = s.ExportCSV(buf, CollectionAccounts, WithColumns("phone", "password"))
+	if !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("invalid error: %v", err)
+	}
+
+	err = s.ExportCSV(buf, "keys")
+	if !errors.Is(err, ErrUnknownCollection) {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestService_ImportCSV_errors(t *testing.T) {
+	header := strings.Join(csvColumns[CollectionFavorites], ",")
+	tests := []struct {
+		data  string
+		line  int
+		field string
+	}{
+		{"id,name\nf1,car\n", 1, "account_id"},
+		{header + "\nf1,1,car,100,auto,,,TJS\nf2,x,car,100,auto,,,TJS\n", 3, "account_id"},
+		{header + "\nf1,1,\"car\n,100,auto,,,TJS\n", 3, ""},
+	}
+
+	for _, test := range tests {
+		s := newTestService()
+		err := s.ImportCSV(strings.NewReader(test.data), CollectionFavorites)
+
+		var parseError *ParseError
+		if !errors.As(err, &parseError) || parseError.Line != test.line || parseError.Field != test.field {
+			t.Errorf("%q: invalid error: %v", test.data, err)
+			continue
+		}
+
+		favorites, err := s.repo().Favorites()
+		if err != nil || len(favorites) != 0 {
+			t.Errorf("%q: favorites imported from an invalid file: %v, %v", test.data, favorites, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package wallet
+
+import "github.com/AlisherGulomzoda/wallet/pkg/types"
+
+func balanceOf(account *types.Account) types.Cash {
+	return types.Cash{Amount: account.Balance, Currency: account.Currency}
+}
+
+func debit(account *types.Account, cash types.Cash) error {
+	balance, err := balanceOf(account).Sub(cash)
+	if err != nil {
+		return err
+	}
+
+	account.Balance = balance.Amount
+	return nil
+}
+
+func credit(account *types.Account, cash types.Cash) error {
+	balance, err := balanceOf(account).Add(cash)
+	if err != nil {
+		return err
+	}
+
+	account.Balance = balance.Amount
+	return nil
+}
+
+// cashOf returns the amount in the currency from the options, or in the
+// currency of the account when the options have none.
+func cashOf(account *types.Account, amount types.Money, o *payOptions) types.Cash {
+	currency := o.currency
+	if currency == "" {
+		currency = account.Currency
+	}
+
+	return types.Cash{Amount: amount, Currency: currency}
+}
+
+// setOriginal records the requested cash and the rate on a payment made
+// in a currency other than the currency of its account.
+func setOriginal(payment *types.Payment, requested types.Cash, rate types.Rate) {
+	if rate == 0 {
+		return
+	}
+
+	payment.OriginalAmount = requested.Amount
+	payment.OriginalCurrency = requested.Currency
+	payment.Rate = rate
+}
+
+// originalOf returns the cash which was requested for the payment.
+func originalOf(payment *types.Payment) types.Cash {
+	if payment.OriginalCurrency == "" {
+		return types.Cash{Amount: payment.Amount, Currency: payment.Currency}
+	}
+
+	return types.Cash{Amount: payment.OriginalAmount, Currency: payment.OriginalCurrency}
+}
@@ -0,0 +1,138 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func (t *testService) addCurrencyAccounts() (*types.Account, *types.Account, error) {
+	tjs, err := t.OpenAccount("+992000000001", types.CurrencyTJS)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	usd, err := t.OpenAccount("+992000000001", types.CurrencyUSD)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = t.Deposit(tjs.ID, 1_000)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = t.Deposit(usd.ID, 100, WithCurrency(types.CurrencyUSD))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tjs, usd, nil
+}
+
+func TestService_OpenAccount_success(t *testing.T) {
+	s := newTestService()
+	tjs, usd, err := s.addCurrencyAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if tjs.Currency != types.CurrencyTJS || usd.Currency != types.CurrencyUSD {
+		t.Errorf("invalid currencies: %v, %v", tjs, usd)
+		return
+	}
+
+	_, err = s.OpenAccount("+992000000001", types.CurrencyUSD)
+	if err != ErrPhoneAlreadyRegitered {
+		t.Error(err)
+	}
+}
+
+func TestService_RegisterAccount_defaultCurrency(t *testing.T) {
+	s := NewService(NewMemoryRepository(), WithDefaultCurrency(types.CurrencyEUR))
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if account.Currency != types.CurrencyEUR {
+		t.Errorf("invalid currency: got %v, want %v", account.Currency, types.CurrencyEUR)
+	}
+}
+
+func TestService_currencyMismatch(t *testing.T) {
+	s := newTestService()
+	tjs, usd, err := s.addCurrencyAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Deposit(tjs.ID, 1, WithCurrency(types.CurrencyUSD))
+	if err != types.ErrCurrencyMismatch {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(usd.ID, 1, "auto", WithCurrency(types.CurrencyTJS))
+	if err != types.ErrCurrencyMismatch {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Transfer(tjs.ID, usd.ID, 1)
+	if err != types.ErrCurrencyMismatch {
+		t.Error(err)
+		return
+	}
+
+	if s.balance(tjs.ID) != 1_000 || s.balance(usd.ID) != 100 {
+		t.Errorf("invalid balances: tjs - %v, usd - %v", s.balance(tjs.ID), s.balance(usd.ID))
+	}
+}
+
+func TestService_SumPayments_currencies(t *testing.T) {
+	s := newTestService()
+	tjs, usd, err := s.addCurrencyAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(tjs.ID, 300, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(usd.ID, 30, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(usd.ID, 20, "auto", WithCurrency(types.CurrencyUSD))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := map[types.Currency]types.Money{types.CurrencyTJS: 300, types.CurrencyUSD: 50}
+	for _, goroutines := range []int{1, 2} {
+		got := s.SumPayments(goroutines)
+		if len(got) != len(want) || got[types.CurrencyTJS] != want[types.CurrencyTJS] || got[types.CurrencyUSD] != want[types.CurrencyUSD] {
+			t.Errorf("invalid sum with %v goroutines: got %v, want %v", goroutines, got, want)
+		}
+	}
+
+	got := map[types.Currency]types.Money{}
+	for progress := range s.SumPaymentsWithProgress() {
+		got[progress.Currency] += progress.Result
+	}
+
+	if len(got) != len(want) || got[types.CurrencyTJS] != want[types.CurrencyTJS] || got[types.CurrencyUSD] != want[types.CurrencyUSD] {
+		t.Errorf("invalid progress sum: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,318 @@
+package wallet
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+	"github.com/google/uuid"
+)
+
+// Event is a change of the state of the Service. The ID and Time of an
+// event are set when the change is saved.
+type Event interface {
+	Header() *EventHeader
+	EventName() string
+}
+
+type EventHeader struct {
+	ID        string
+	AccountID int64
+	Time      time.Time
+}
+
+func (h *EventHeader) Header() *EventHeader {
+	return h
+}
+
+type AccountRegistered struct {
+	EventHeader
+	Phone    types.Phone
+	Currency types.Currency
+}
+
+type AccountStatusChanged struct {
+	EventHeader
+	From types.AccountStatus
+	To   types.AccountStatus
+}
+
+type LimitsChanged struct {
+	EventHeader
+	Limits types.Limits
+}
+
+type OverdraftChanged struct {
+	EventHeader
+	Overdraft types.Money
+}
+
+type Deposited struct {
+	EventHeader
+	Amount   types.Money
+	Currency types.Currency
+	Balance  types.Money
+}
+
+// PaymentCreated is published for payments and for both sides of a
+// transfer.
+type PaymentCreated struct {
+	EventHeader
+	PaymentID string
+	Type      types.PaymentType
+	Category  types.PaymentCategoty
+	Amount    types.Money
+	Currency  types.Currency
+	LinkedID  string
+	Balance   types.Money
+}
+
+type PaymentConfirmed struct {
+	EventHeader
+	PaymentID string
+}
+
+type PaymentRejected struct {
+	EventHeader
+	PaymentID string
+	Amount    types.Money
+	Currency  types.Currency
+	Balance   types.Money
+}
+
+type PaymentRefunded struct {
+	EventHeader
+	PaymentID string
+	Amount    types.Money
+	Currency  types.Currency
+	Refunded  types.Money
+	Balance   types.Money
+}
+
+type FavoriteCreated struct {
+	EventHeader
+	FavoriteID string
+	PaymentID  string
+	Name       string
+	Amount     types.Money
+	Category   types.PaymentCategoty
+	Currency   types.Currency
+}
+
+func (*AccountRegistered) EventName() string    { return "AccountRegistered" }
+func (*AccountStatusChanged) EventName() string { return "AccountStatusChanged" }
+func (*LimitsChanged) EventName() string        { return "LimitsChanged" }
+func (*OverdraftChanged) EventName() string     { return "OverdraftChanged" }
+func (*Deposited) EventName() string            { return "Deposited" }
+func (*PaymentCreated) EventName() string       { return "PaymentCreated" }
+func (*PaymentConfirmed) EventName() string     { return "PaymentConfirmed" }
+func (*PaymentRejected) EventName() string      { return "PaymentRejected" }
+func (*PaymentRefunded) EventName() string      { return "PaymentRefunded" }
+func (*FavoriteCreated) EventName() string      { return "FavoriteCreated" }
+
+// eventTypes makes an empty event by its name, to decode events saved as
+// JSON.
+var eventTypes = map[string]func() Event{
+	"AccountRegistered":    func() Event { return &AccountRegistered{} },
+	"AccountStatusChanged": func() Event { return &AccountStatusChanged{} },
+	"LimitsChanged":        func() Event { return &LimitsChanged{} },
+	"OverdraftChanged":     func() Event { return &OverdraftChanged{} },
+	"Deposited":            func() Event { return &Deposited{} },
+	"PaymentCreated":       func() Event { return &PaymentCreated{} },
+	"PaymentConfirmed":     func() Event { return &PaymentConfirmed{} },
+	"PaymentRejected":      func() Event { return &PaymentRejected{} },
+	"PaymentRefunded":      func() Event { return &PaymentRefunded{} },
+	"FavoriteCreated":      func() Event { return &FavoriteCreated{} },
+}
+
+// EventBus delivers published events to its subscriptions in the order
+// they were published. A subscription with a full buffer drops events
+// instead of blocking the publisher.
+type EventBus struct {
+	mu            sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscriptions: make(map[*Subscription]struct{})}
+}
+
+type Subscription struct {
+	bus     *EventBus
+	events  chan Event
+	dropped int64
+	done    chan struct{}
+}
+
+// Subscribe returns a subscription which buffers up to buffer events.
+func (b *EventBus) Subscribe(buffer int) *Subscription {
+	subscription := &Subscription{
+		bus:    b,
+		events: make(chan Event, buffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscriptions == nil {
+		b.subscriptions = make(map[*Subscription]struct{})
+	}
+	b.subscriptions[subscription] = struct{}{}
+
+	return subscription
+}
+
+// SubscribeFunc calls fn for every event in its own goroutine, one event
+// at a time.
+func (b *EventBus) SubscribeFunc(buffer int, fn func(event Event)) *Subscription {
+	subscription := b.Subscribe(buffer)
+	subscription.done = make(chan struct{})
+	go func() {
+		defer close(subscription.done)
+		for event := range subscription.events {
+			fn(event)
+		}
+	}()
+
+	return subscription
+}
+
+func (b *EventBus) Publish(events ...Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, event := range events {
+		for subscription := range b.subscriptions {
+			select {
+			case subscription.events <- event:
+			default:
+				atomic.AddInt64(&subscription.dropped, 1)
+			}
+		}
+	}
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events dropped because the buffer of the
+// subscription was full.
+func (s *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// Unsubscribe stops the delivery of events and closes the channel of the
+// subscription. For a SubscribeFunc subscription it waits until fn has
+// handled the buffered events, so it must not be called from fn.
+func (s *Subscription) Unsubscribe() {
+	s.bus.mu.Lock()
+	if _, ok := s.bus.subscriptions[s]; ok {
+		delete(s.bus.subscriptions, s)
+		close(s.events)
+	}
+	s.bus.mu.Unlock()
+
+	if s.done != nil {
+		<-s.done
+	}
+}
+
+func paymentCreated(payment *types.Payment, account *types.Account) *PaymentCreated {
+	return &PaymentCreated{
+		EventHeader: EventHeader{AccountID: account.ID},
+		PaymentID:   payment.ID,
+		Type:        payment.Type,
+		Category:    payment.Category,
+		Amount:      payment.Amount,
+		Currency:    payment.Currency,
+		LinkedID:    payment.LinkedID,
+		Balance:     account.Balance,
+	}
+}
+
+// paymentReturned describes a refund of the amount of the payment which
+// moved it to the status.
+func paymentReturned(payment *types.Payment, account *types.Account, amount types.Money, status types.PaymentStatus) Event {
+	header := EventHeader{AccountID: account.ID}
+	if status == types.PaymentStatusFail {
+		return &PaymentRejected{
+			EventHeader: header,
+			PaymentID:   payment.ID,
+			Amount:      amount,
+			Currency:    payment.Currency,
+			Balance:     account.Balance,
+		}
+	}
+
+	return &PaymentRefunded{
+		EventHeader: header,
+		PaymentID:   payment.ID,
+		Amount:      amount,
+		Currency:    payment.Currency,
+		Refunded:    payment.Refunded,
+		Balance:     account.Balance,
+	}
+}
+
+func statusChanged(account *types.Account, from types.AccountStatus) *AccountStatusChanged {
+	return &AccountStatusChanged{
+		EventHeader: EventHeader{AccountID: account.ID},
+		From:        from,
+		To:          account.Status,
+	}
+}
+
+// stampEvents sets the ID and the time of the events which are saved.
+func stampEvents(events []Event, now time.Time) {
+	for _, event := range events {
+		header := event.Header()
+		if header.ID == "" {
+			header.ID = uuid.New().String()
+		}
+		if header.Time.IsZero() {
+			header.Time = now
+		}
+	}
+}
+
+// copyEvent returns a copy of the event, so the outbox does not share it
+// with the subscriptions.
+func copyEvent(event Event) Event {
+	switch event := event.(type) {
+	case *AccountRegistered:
+		result := *event
+		return &result
+	case *AccountStatusChanged:
+		result := *event
+		return &result
+	case *LimitsChanged:
+		result := *event
+		result.Limits = copyLimits(event.Limits)
+		return &result
+	case *OverdraftChanged:
+		result := *event
+		return &result
+	case *Deposited:
+		result := *event
+		return &result
+	case *PaymentCreated:
+		result := *event
+		return &result
+	case *PaymentConfirmed:
+		result := *event
+		return &result
+	case *PaymentRejected:
+		result := *event
+		return &result
+	case *PaymentRefunded:
+		result := *event
+		return &result
+	case *FavoriteCreated:
+		result := *event
+		return &result
+	}
+
+	return event
+}
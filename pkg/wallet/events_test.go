@@ -0,0 +1,294 @@
+package wallet
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func newEventsTestService(bus *EventBus) *testService {
+	return &testService{Service: NewService(NewMemoryRepository(), WithEventBus(bus))}
+}
+
+func eventNames(events []Event) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.EventName()
+	}
+
+	return names
+}
+
+func receiveEvents(subscription *Subscription) []Event {
+	subscription.Unsubscribe()
+
+	events := []Event{}
+	for event := range subscription.Events() {
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestService_events(t *testing.T) {
+	bus := NewEventBus()
+	subscription := bus.Subscribe(100)
+	s := newEventsTestService(bus)
+
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Confirm(payments[0].ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Refund(payments[0].ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.FavoritePayment(payments[0].ID, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment, err := s.Pay(account.ID, 100, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetLimits(account.ID, types.Limits{Daily: 1_000})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetOverdraft(account.ID, 1_000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Freeze(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Unfreeze(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.CloseWithPayout(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	events := receiveEvents(subscription)
+	want := []string{
+		"AccountRegistered",
+		"Deposited",
+		"PaymentCreated",
+		"PaymentConfirmed",
+		"PaymentRefunded",
+		"FavoriteCreated",
+		"PaymentCreated",
+		"PaymentRejected",
+		"LimitsChanged",
+		"OverdraftChanged",
+		"AccountStatusChanged",
+		"AccountStatusChanged",
+		"PaymentCreated",
+		"AccountStatusChanged",
+	}
+	if !reflect.DeepEqual(eventNames(events), want) {
+		t.Errorf("invalid events: got - %v, want - %v", eventNames(events), want)
+		return
+	}
+
+	ids := make(map[string]bool)
+	for _, event := range events {
+		header := event.Header()
+		if header.ID == "" || ids[header.ID] || header.Time.IsZero() || header.AccountID != account.ID {
+			t.Errorf("invalid event header: %v", header)
+		}
+		ids[header.ID] = true
+	}
+
+	rejected := events[7].(*PaymentRejected)
+	if rejected.PaymentID != payment.ID || rejected.Amount != 100 || rejected.Balance != 9_001_00 {
+		t.Errorf("invalid rejected event: %v", rejected)
+	}
+
+	closed := events[13].(*AccountStatusChanged)
+	if closed.From != types.AccountStatusActive || closed.To != types.AccountStatusClosed {
+		t.Errorf("invalid status event: %v", closed)
+	}
+}
+
+func TestService_events_transfer(t *testing.T) {
+	bus := NewEventBus()
+	s := newEventsTestService(bus)
+	from, to, err := s.addTransferAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	subscription := bus.Subscribe(10)
+	outgoing, err := s.Transfer(from.ID, to.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	events := receiveEvents(subscription)
+	if len(events) != 2 {
+		t.Errorf("invalid events: %v", eventNames(events))
+		return
+	}
+
+	sent, received := events[0].(*PaymentCreated), events[1].(*PaymentCreated)
+	if sent.AccountID != from.ID || sent.PaymentID != outgoing.ID || received.AccountID != to.ID || received.LinkedID != outgoing.ID {
+		t.Errorf("invalid transfer events: %v, %v", sent, received)
+	}
+}
+
+func TestEventBus_slowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	slow := bus.Subscribe(1)
+	s := newEventsTestService(bus)
+
+	account, err := s.addAccountWithBalance("+992000000001", 1_000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i := 0; i < 10; i++ {
+		_, err = s.Pay(account.ID, 1, "auto")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if slow.Dropped() != 11 {
+		t.Errorf("invalid dropped events: %v", slow.Dropped())
+	}
+
+	events := receiveEvents(slow)
+	if len(events) != 1 || events[0].EventName() != "AccountRegistered" {
+		t.Errorf("invalid buffered events: %v", eventNames(events))
+	}
+}
+
+func TestEventBus_SubscribeFunc_orderPerAccount(t *testing.T) {
+	bus := NewEventBus()
+	var mu sync.Mutex
+	balances := make(map[int64][]types.Money)
+	subscription := bus.SubscribeFunc(1_000, func(event Event) {
+		if created, ok := event.(*PaymentCreated); ok {
+			mu.Lock()
+			balances[created.AccountID] = append(balances[created.AccountID], created.Balance)
+			mu.Unlock()
+		}
+	})
+	s := newEventsTestService(bus)
+
+	const accounts, payments = 4, 50
+	ids := make([]int64, accounts)
+	for i := range ids {
+		account, err := s.addAccountWithBalance(types.Phone("+99200000000"+string(rune('1'+i))), payments)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ids[i] = account.ID
+	}
+
+	wg := sync.WaitGroup{}
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			for i := 0; i < payments; i++ {
+				_, err := s.Pay(id, 1, "auto")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(id)
+	}
+	wg.Wait()
+	subscription.Unsubscribe()
+
+	for _, id := range ids {
+		got := balances[id]
+		if len(got) != payments {
+			t.Errorf("account %v: invalid number of events: %v", id, len(got))
+			continue
+		}
+
+		for i, balance := range got {
+			if balance != types.Money(payments-1-i) {
+				t.Errorf("account %v: events out of order: %v", id, got)
+				break
+			}
+		}
+	}
+}
+
+func TestService_events_outboxCopy(t *testing.T) {
+	bus := NewEventBus()
+	subscription := bus.Subscribe(10)
+	s := newEventsTestService(bus)
+
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetLimits(account.ID, types.Limits{Categories: map[types.PaymentCategoty]types.Money{"food": 300}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	events := receiveEvents(subscription)
+	published := events[len(events)-1].(*LimitsChanged)
+	published.Limits.Categories["food"] = 1
+	published.Limits.Single = 1
+
+	entries, err := s.repo().OutboxEntries(0, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	saved := entries[len(entries)-1].Event.(*LimitsChanged)
+	if saved.Limits.Categories["food"] != 300 || saved.Limits.Single != 0 {
+		t.Errorf("outbox event changed by a subscriber: %v", saved.Limits)
+	}
+}
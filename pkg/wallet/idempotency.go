@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var (
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	ErrIdempotencyKeyReused   = errors.New("idempotency key reused with other parameters")
+)
+
+// fingerprint identifies the parameters of a money-moving call.
+func fingerprint(values ...interface{}) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%#v", values)))
+	return hex.EncodeToString(sum[:])
+}
+
+// replay returns the record of an earlier call made with the key, or nil
+// when the key is empty or was not used yet.
+func (s *Service) replay(key, fingerprint string) (*types.IdempotencyKey, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	record, err := s.repo().FindIdempotencyKey(key)
+	if err == ErrIdempotencyKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	return record, nil
+}
+
+// keysOf returns the record to save for a call made with the key.
+func keysOf(key, fingerprint, paymentID string) []*types.IdempotencyKey {
+	if key == "" {
+		return nil
+	}
+
+	return []*types.IdempotencyKey{{Key: key, Fingerprint: fingerprint, PaymentID: paymentID}}
+}
+
+func copyKey(key *types.IdempotencyKey) *types.IdempotencyKey {
+	result := *key
+	return &result
+}
@@ -0,0 +1,161 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func TestService_Pay_idempotent(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992000000001", 1_000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	first, err := s.Pay(account.ID, 100, "auto", WithIdempotencyKey("pay-1"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	second, err := s.Pay(account.ID, 100, "auto", WithIdempotencyKey("pay-1"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if second.ID != first.ID {
+		t.Errorf("payment repeated: first - %v, second - %v", first, second)
+	}
+
+	if s.balance(account.ID) != 900 {
+		t.Errorf("invalid balance: %v", s.balance(account.ID))
+	}
+
+	_, err = s.Pay(account.ID, 200, "auto", WithIdempotencyKey("pay-1"))
+	if err != ErrIdempotencyKeyReused {
+		t.Error(err)
+		return
+	}
+
+	third, err := s.Pay(account.ID, 100, "auto", WithIdempotencyKey("pay-2"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if third.ID == first.ID || s.balance(account.ID) != 800 {
+		t.Errorf("payment with a new key not made: %v", third)
+	}
+}
+
+func TestService_Deposit_idempotent(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		err = s.Deposit(account.ID, 100, WithIdempotencyKey("deposit-1"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if s.balance(account.ID) != 100 {
+		t.Errorf("invalid balance: %v", s.balance(account.ID))
+	}
+
+	_, err = s.Pay(account.ID, 100, "auto", WithIdempotencyKey("deposit-1"))
+	if err != ErrIdempotencyKeyReused {
+		t.Error(err)
+	}
+}
+
+func TestService_PayFromFavorite_idempotent(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	favorite, err := s.FavoritePayment(payments[0].ID, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	first, err := s.PayFromFavorite(favorite.ID, WithIdempotencyKey("favorite-1"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	second, err := s.PayFromFavorite(favorite.ID, WithIdempotencyKey("favorite-1"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if second.ID != first.ID || s.balance(account.ID) != 8_000_00 {
+		t.Errorf("payment repeated: first - %v, second - %v", first, second)
+	}
+}
+
+func TestService_Import_idempotencyKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992000000001", 1_000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment, err := s.Pay(account.ID, 100, "auto", WithIdempotencyKey("pay-1"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	got, err := imported.Pay(account.ID, 100, "auto", WithIdempotencyKey("pay-1"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got.ID != payment.ID || imported.balance(account.ID) != 900 {
+		t.Errorf("payment repeated after import: got - %v, want - %v", got, payment)
+	}
+
+	err = imported.Deposit(account.ID, 1, WithCurrency(types.CurrencyTJS), WithIdempotencyKey("pay-1"))
+	if err != ErrIdempotencyKeyReused {
+		t.Error(err)
+	}
+}
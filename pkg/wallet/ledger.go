@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+	"github.com/google/uuid"
+)
+
+// ExternalAccountID is the ledger account for money coming from or going
+// outside of the wallet: deposits, payments and refunds.
+const ExternalAccountID int64 = 0
+
+// Mismatch is reported by Reconcile when the cached balance of an account
+// differs from the balance computed from the ledger.
+type Mismatch struct {
+	AccountID int64
+	Balance   types.Money
+	Ledger    types.Money
+}
+
+// newPosting moves cash from the debit account to the credit account.
+func (s *Service) newPosting(debit, credit int64, cash types.Cash, paymentID string) *types.Posting {
+	return &types.Posting{
+		ID:        uuid.New().String(),
+		Debit:     debit,
+		Credit:    credit,
+		Amount:    cash.Amount,
+		Currency:  cash.Currency,
+		PaymentID: paymentID,
+		CreatedAt: s.now(),
+	}
+}
+
+// adjustmentPosting brings the ledger of the account from balance to target.
+func (s *Service) adjustmentPosting(account *types.Account, balance, target types.Money) *types.Posting {
+	if target > balance {
+		return s.newPosting(ExternalAccountID, account.ID, types.Cash{Amount: target - balance, Currency: account.Currency}, "")
+	}
+
+	return s.newPosting(account.ID, ExternalAccountID, types.Cash{Amount: balance - target, Currency: account.Currency}, "")
+}
+
+func (s *Service) Ledger() ([]types.Posting, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.repo().Postings()
+}
+
+// Reconcile recomputes balances of all accounts from the ledger and
+// returns the accounts whose cached balance does not match.
+func (s *Service) Reconcile() ([]Mismatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	postings, err := s.repo().Postings()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.repo().Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[int64]types.Money)
+	for _, posting := range postings {
+		balances[posting.Debit] -= posting.Amount
+		balances[posting.Credit] += posting.Amount
+	}
+
+	mismatches := []Mismatch{}
+	for _, account := range accounts {
+		if balances[account.ID] != account.Balance {
+			mismatches = append(mismatches, Mismatch{
+				AccountID: account.ID,
+				Balance:   account.Balance,
+				Ledger:    balances[account.ID],
+			})
+		}
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,111 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func TestService_Ledger_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(payments[0].ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	postings, err := s.Ledger()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := []struct {
+		debit  int64
+		credit int64
+		amount types.Money
+	}{
+		{debit: ExternalAccountID, credit: account.ID, amount: defaultTestAccount.balance},
+		{debit: account.ID, credit: ExternalAccountID, amount: payments[0].Amount},
+		{debit: ExternalAccountID, credit: account.ID, amount: payments[0].Amount},
+	}
+
+	if len(postings) != len(want) {
+		t.Errorf("invalid postings count: got %v, want %v", len(postings), len(want))
+		return
+	}
+
+	for i, posting := range postings {
+		if posting.Debit != want[i].debit || posting.Credit != want[i].credit || posting.Amount != want[i].amount {
+			t.Errorf("invalid posting %v: got %v, want %v", i, posting, want[i])
+		}
+	}
+
+	if postings[1].PaymentID != payments[0].ID || postings[2].PaymentID != payments[0].ID {
+		t.Errorf("postings must reference payment %v: %v", payments[0].ID, postings)
+	}
+}
+
+func TestService_Reconcile_success(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Repeat(payments[0].ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	mismatches, err := s.Reconcile()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(mismatches) != 0 {
+		t.Errorf("unexpected mismatches: %v", mismatches)
+	}
+}
+
+func TestService_Reconcile_mismatch(t *testing.T) {
+	s := newTestService()
+	account, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	saved, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := Mismatch{AccountID: account.ID, Balance: saved.Balance + 1, Ledger: saved.Balance}
+	saved.Balance++
+	err = s.repo().Save(&Batch{Accounts: []*types.Account{saved}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	mismatches, err := s.Reconcile()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(mismatches) != 1 || mismatches[0] != want {
+		t.Errorf("invalid mismatches: got %v, want %v", mismatches, want)
+	}
+}
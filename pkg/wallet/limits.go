@@ -0,0 +1,222 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var (
+	ErrLimitExceeded = errors.New("limit exceeded")
+	ErrInvalidLimit  = errors.New("limit must not be negative")
+)
+
+type LimitKind string
+
+const (
+	LimitSingle   LimitKind = "single"
+	LimitDaily    LimitKind = "daily"
+	LimitMonthly  LimitKind = "monthly"
+	LimitCategory LimitKind = "category"
+)
+
+// LimitError is returned when a payment would break a limit of the
+// account. It matches ErrLimitExceeded with errors.Is.
+type LimitError struct {
+	AccountID int64
+	Kind      LimitKind
+	Category  types.PaymentCategoty
+	Limit     types.Money
+	Spent     types.Money
+	Amount    types.Money
+}
+
+func (e *LimitError) Error() string {
+	kind := string(e.Kind)
+	if e.Kind == LimitCategory {
+		kind += " " + string(e.Category)
+	}
+
+	return fmt.Sprintf("account %d: %s limit %d exceeded: spent %d, paying %d", e.AccountID, kind, e.Limit, e.Spent, e.Amount)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// SetLimits replaces the limits of the account. Category limits cap the
+// monthly total of payments in the category.
+func (s *Service) SetLimits(accountID int64, limits types.Limits) error {
+	if limits.Single < 0 || limits.Daily < 0 || limits.Monthly < 0 {
+		return ErrInvalidLimit
+	}
+
+	for _, limit := range limits.Categories {
+		if limit < 0 {
+			return ErrInvalidLimit
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, err := s.repo().FindAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	account.Limits = copyLimits(limits)
+
+	return s.save(&Batch{
+		Accounts: []*types.Account{account},
+		Events: []Event{&LimitsChanged{
+			EventHeader: EventHeader{AccountID: account.ID},
+			Limits:      copyLimits(limits),
+		}},
+	})
+}
+
+// checkLimits checks that a payment of the cash in the category keeps
+// the account within its limits. Payments and outgoing transfers count
+// as spent.
+func (s *Service) checkLimits(account *types.Account, category types.PaymentCategoty, cash types.Cash) error {
+	limits := account.Limits
+	limitError := func(kind LimitKind, limit, spent types.Money) error {
+		return &LimitError{
+			AccountID: account.ID,
+			Kind:      kind,
+			Category:  category,
+			Limit:     limit,
+			Spent:     spent,
+			Amount:    cash.Amount,
+		}
+	}
+
+	if limits.Single != 0 && cash.Amount > limits.Single {
+		return limitError(LimitSingle, limits.Single, 0)
+	}
+
+	categoryLimit := limits.Categories[category]
+	if limits.Daily == 0 && limits.Monthly == 0 && categoryLimit == 0 {
+		return nil
+	}
+
+	payments, err := s.repo().FindPaymentsByAccountID(account.ID)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	year, month, day := now.Date()
+	dayStart := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+
+	var daily, monthly, byCategory types.Money
+	for _, payment := range payments {
+		spending := payment.Type == types.PaymentTypePayment || payment.Type == types.PaymentTypeTransferOut
+		if !spending || payment.Status == types.PaymentStatusFail {
+			continue
+		}
+
+		if payment.CreatedAt.Before(monthStart) {
+			continue
+		}
+
+		spent := payment.Amount - payment.Refunded
+		monthly += spent
+		if payment.Category == category {
+			byCategory += spent
+		}
+		if !payment.CreatedAt.Before(dayStart) {
+			daily += spent
+		}
+	}
+
+	if limits.Daily != 0 && daily+cash.Amount > limits.Daily {
+		return limitError(LimitDaily, limits.Daily, daily)
+	}
+
+	if limits.Monthly != 0 && monthly+cash.Amount > limits.Monthly {
+		return limitError(LimitMonthly, limits.Monthly, monthly)
+	}
+
+	if categoryLimit != 0 && byCategory+cash.Amount > categoryLimit {
+		return limitError(LimitCategory, categoryLimit, byCategory)
+	}
+
+	return nil
+}
+
+func copyLimits(limits types.Limits) types.Limits {
+	if limits.Categories == nil {
+		return limits
+	}
+
+	categories := make(map[types.PaymentCategoty]types.Money, len(limits.Categories))
+	for category, limit := range limits.Categories {
+		categories[category] = limit
+	}
+	limits.Categories = categories
+
+	return limits
+}
+
+// formatLimits writes the limits as the single, daily, monthly and
+// categories fields, where categories are "category=limit" pairs joined
+// with commas. The commas and equal signs in the categories are escaped.
+func formatLimits(limits types.Limits) []string {
+	categories := make([]string, 0, len(limits.Categories))
+	for category, limit := range limits.Categories {
+		categories = append(categories, escape(string(category), ",=")+"="+strconv.Itoa(int(limit)))
+	}
+	sort.Strings(categories)
+
+	return []string{
+		strconv.Itoa(int(limits.Single)),
+		strconv.Itoa(int(limits.Daily)),
+		strconv.Itoa(int(limits.Monthly)),
+		strings.Join(categories, ","),
+	}
+}
+
+func parseLimits(data []string) (types.Limits, error) {
+	values := make([]types.Money, 3)
+	for i := range values {
+		value, err := strconv.Atoi(data[i])
+		if err != nil {
+			return types.Limits{}, err
+		}
+		values[i] = types.Money(value)
+	}
+
+	limits := types.Limits{Single: values[0], Daily: values[1], Monthly: values[2]}
+	if data[3] == "" {
+		return limits, nil
+	}
+
+	limits.Categories = make(map[types.PaymentCategoty]types.Money)
+	for _, pair := range splitEscaped(data[3], ',') {
+		parts := splitEscaped(pair, '=')
+		if len(parts) != 2 {
+			return types.Limits{}, fmt.Errorf("invalid category limit %q", pair)
+		}
+
+		category, err := unescape(parts[0])
+		if err != nil {
+			return types.Limits{}, err
+		}
+
+		limit, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return types.Limits{}, err
+		}
+		limits.Categories[types.PaymentCategoty(category)] = types.Money(limit)
+	}
+
+	return limits, nil
+}
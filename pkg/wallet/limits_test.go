@@ -0,0 +1,223 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func TestService_SetLimits_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetLimits(account.ID, types.Limits{Daily: -1})
+	if err != ErrInvalidLimit {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetLimits(account.ID+1, types.Limits{})
+	if err != ErrAccountNotFound {
+		t.Error(err)
+	}
+}
+
+func TestService_Pay_limits(t *testing.T) {
+	clock := newTestClock()
+	s := newTestService()
+	s.clock = clock
+	account, err := s.addAccountWithBalance("+992000000001", 10_000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetLimits(account.ID, types.Limits{
+		Single:     500,
+		Daily:      800,
+		Monthly:    1_500,
+		Categories: map[types.PaymentCategoty]types.Money{"food": 300},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkLimit := func(err error, kind LimitKind, spent types.Money) {
+		t.Helper()
+		var limitError *LimitError
+		if !errors.As(err, &limitError) || !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("invalid error: %v", err)
+			return
+		}
+
+		if limitError.Kind != kind || limitError.Spent != spent {
+			t.Errorf("invalid limit error: %v", limitError)
+		}
+	}
+
+	_, err = s.Pay(account.ID, 501, "auto")
+	checkLimit(err, LimitSingle, 0)
+
+	payment, err := s.Pay(account.ID, 500, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(account.ID, 301, "auto")
+	checkLimit(err, LimitDaily, 500)
+
+	_, err = s.Repeat(payment.ID)
+	checkLimit(err, LimitDaily, 500)
+
+	clock.add(24 * time.Hour)
+	_, err = s.Pay(account.ID, 200, "food")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(account.ID, 101, "food")
+	checkLimit(err, LimitCategory, 200)
+
+	_, err = s.Repeat(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	clock.add(24 * time.Hour)
+	_, err = s.Pay(account.ID, 301, "auto")
+	checkLimit(err, LimitMonthly, 1_200)
+
+	clock.add(31 * 24 * time.Hour)
+	_, err = s.Pay(account.ID, 500, "auto")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestService_PayFromFavorite_limits(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	favorite, err := s.FavoritePayment(payments[0].ID, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetLimits(account.ID, types.Limits{Single: 100})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.PayFromFavorite(favorite.ID)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Error(err)
+	}
+}
+
+func TestService_Transfer_limits(t *testing.T) {
+	s := newTestService()
+	from, to, err := s.addTransferAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetLimits(from.ID, types.Limits{Daily: 300})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(from.ID, 200, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Transfer(from.ID, to.ID, 101)
+	var limitError *LimitError
+	if !errors.As(err, &limitError) || limitError.Kind != LimitDaily || limitError.Spent != 200 {
+		t.Errorf("invalid error: %v", err)
+		return
+	}
+
+	_, err = s.Transfer(from.ID, to.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(from.ID, 1, "auto")
+	if !errors.As(err, &limitError) || limitError.Spent != 300 {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestService_Import_limits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	limits := types.Limits{
+		Single:     100,
+		Monthly:    1_000,
+		Categories: map[types.PaymentCategoty]types.Money{"auto": 200, "food": 300},
+	}
+	err = s.SetLimits(account.ID, limits)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	got, err := imported.FindAccountByID(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !reflect.DeepEqual(got.Limits, limits) {
+		t.Errorf("invalid limits: got - %v, want - %v", got.Limits, limits)
+	}
+}
@@ -0,0 +1,168 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestVersion is the version of the format of the dumps written by
+// Export.
+const ManifestVersion = 1
+
+const (
+	manifestFile  = "manifest.json"
+	accountsFile  = "accounts.dump"
+	paymentsFile  = "payments.dump"
+	favoritesFile = "favorites.dump"
+	keysFile      = "keys.dump"
+)
+
+// dumpFiles are the dumps in the order they are applied.
+var dumpFiles = []string{accountsFile, paymentsFile, favoritesFile, keysFile}
+
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+// ManifestError is returned by Import when the dumps don't match the
+// manifest. It matches ErrInvalidManifest with errors.Is.
+type ManifestError struct {
+	File   string
+	Reason string
+}
+
+func (e *ManifestError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("%v: %s", ErrInvalidManifest, e.Reason)
+	}
+
+	return fmt.Sprintf("%v: %s: %s", ErrInvalidManifest, e.File, e.Reason)
+}
+
+func (e *ManifestError) Is(target error) bool {
+	return target == ErrInvalidManifest
+}
+
+// Manifest describes the dumps written by one Export.
+type Manifest struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Files     []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Name    string `json:"name"`
+	Records int    `json:"records"`
+	SHA256  string `json:"sha256"`
+}
+
+func (d dump) entry() ManifestFile {
+	sum := sha256.Sum256([]byte(d.data))
+	return ManifestFile{Name: d.name, Records: d.records, SHA256: hex.EncodeToString(sum[:])}
+}
+
+func writeManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return actionByFile(filepath.Join(dir, manifestFile), string(data)+"\n")
+}
+
+// ReadManifest reads the manifest of the dumps in the dir.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, &ManifestError{File: manifestFile, Reason: err.Error()}
+	}
+
+	return manifest, nil
+}
+
+// readDumps reads the dumps in the dir and checks them against the
+// manifest, when there is one.
+func readDumps(dir string) ([]dump, error) {
+	manifest, err := ReadManifest(dir)
+	if os.IsNotExist(err) {
+		return readLegacyDumps(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Version < 1 || manifest.Version > ManifestVersion {
+		return nil, &ManifestError{Reason: fmt.Sprintf("unsupported version %d", manifest.Version)}
+	}
+
+	entries := make(map[string]ManifestFile, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		entries[entry.Name] = entry
+	}
+
+	files := []dump{}
+	for _, name := range dumpFiles {
+		entry, ok := entries[name]
+		if !ok {
+			return nil, &ManifestError{File: name, Reason: "not in the manifest"}
+		}
+		delete(entries, name)
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		file := dump{name: name, data: string(data), records: countRecords(string(data))}
+		if file.entry().SHA256 != entry.SHA256 {
+			return nil, &ManifestError{File: name, Reason: "checksum mismatch"}
+		}
+
+		if file.records != entry.Records {
+			return nil, &ManifestError{File: name, Reason: fmt.Sprintf("%d records, want %d", file.records, entry.Records)}
+		}
+
+		files = append(files, file)
+	}
+
+	for name := range entries {
+		return nil, &ManifestError{File: name, Reason: "unknown file"}
+	}
+
+	return files, nil
+}
+
+// readLegacyDumps reads the dumps written without a manifest, skipping
+// the missing ones.
+func readLegacyDumps(dir string) ([]dump, error) {
+	files := []dump{}
+	for _, name := range dumpFiles {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, dump{name: name, data: string(data), records: countRecords(string(data))})
+	}
+
+	return files, nil
+}
+
+func countRecords(data string) int {
+	return strings.Count(data, "\n")
+}
@@ -0,0 +1,234 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func exportTestService(t *testing.T) (*testService, string, func()) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestService()
+	_, _, err = s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+// rewriteDump replaces the dump and updates the manifest to match it.
+func rewriteDump(t *testing.T, dir string, name string, data string) {
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, entry := range manifest.Files {
+		if entry.Name == name {
+			manifest.Files[i] = dump{name: name, data: data, records: countRecords(data)}.entry()
+		}
+	}
+
+	err = writeManifest(dir, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestService_Export_manifest(t *testing.T) {
+	_, dir, cleanup := exportTestService(t)
+	defer cleanup()
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	records := map[string]int{accountsFile: 1, paymentsFile: 1, favoritesFile: 0, keysFile: 0}
+	if manifest.Version != ManifestVersion || manifest.CreatedAt.IsZero() || len(manifest.Files) != len(records) {
+		t.Errorf("invalid manifest: %+v", manifest)
+		return
+	}
+
+	for _, entry := range manifest.Files {
+		if entry.Records != records[entry.Name] || len(entry.SHA256) != 64 {
+			t.Errorf("invalid manifest entry: %+v", entry)
+		}
+
+		_, err := os.Stat(filepath.Join(dir, entry.Name))
+		if err != nil {
+			t.Errorf("dump not written: %v", err)
+		}
+	}
+}
+
+func TestService_Import_manifest(t *testing.T) {
+	s, dir, cleanup := exportTestService(t)
+	defer cleanup()
+
+	imported := newTestService()
+	err := imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	account, err := s.FindAccountByID(1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if balance := imported.balance(account.ID); balance != account.Balance {
+		t.Errorf("invalid balance: got - %v, want - %v", balance, account.Balance)
+	}
+
+	payments, err := imported.FilterPayments(account.ID, 1)
+	if err != nil || len(payments) != 1 {
+		t.Errorf("invalid payments: %v, %v", payments, err)
+	}
+}
+
+func TestService_Import_keepsAccountIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	writeDump := func(name string, data string) {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeDump(accountsFile, "7;+992000000001;1000\n")
+	writeDump(paymentsFile, "p1;7;100;auto;OK\n")
+
+	s := newTestService()
+	err = s.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payments, err := s.FilterPayments(7, 1)
+	if err != nil || len(payments) != 1 {
+		t.Errorf("payment lost its account: %v, %v", payments, err)
+	}
+}
+
+func TestService_Import_checksumMismatch(t *testing.T) {
+	_, dir, cleanup := exportTestService(t)
+	defer cleanup()
+
+	err := ioutil.WriteFile(filepath.Join(dir, paymentsFile), []byte("x;1;100;auto;OK\n"), 0644)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	err = imported.Import(dir)
+	var manifestErr *ManifestError
+	if !errors.Is(err, ErrInvalidManifest) || !errors.As(err, &manifestErr) || manifestErr.File != paymentsFile {
+		t.Errorf("invalid error: %v", err)
+		return
+	}
+
+	accounts, err := imported.repo().Accounts()
+	if err != nil || len(accounts) != 0 {
+		t.Errorf("accounts imported from a corrupt export: %v, %v", accounts, err)
+	}
+}
+
+func TestService_Import_allOrNothing(t *testing.T) {
+	_, dir, cleanup := exportTestService(t)
+	defer cleanup()
+
+	rewriteDump(t, dir, favoritesFile, "f1;x;car;100;auto\n")
+
+	imported := newTestService()
+	err := imported.Import(dir)
+	if err == nil {
+		t.Error("invalid favorite imported")
+		return
+	}
+
+	accounts, err := imported.repo().Accounts()
+	if err != nil || len(accounts) != 0 {
+		t.Errorf("accounts imported with an invalid favorite: %v, %v", accounts, err)
+	}
+
+	payments, err := imported.repo().Payments()
+	if err != nil || len(payments) != 0 {
+		t.Errorf("payments imported with an invalid favorite: %v, %v", payments, err)
+	}
+}
+
+func TestService_Import_unsupportedVersion(t *testing.T) {
+	_, dir, cleanup := exportTestService(t)
+	defer cleanup()
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	manifest.Version = ManifestVersion + 1
+	err = writeManifest(dir, manifest)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = newTestService().Import(dir)
+	if !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestService_Import_recordCount(t *testing.T) {
+	_, dir, cleanup := exportTestService(t)
+	defer cleanup()
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	manifest.Files[0].Records++
+	err = writeManifest(dir, manifest)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = newTestService().Import(dir)
+	if !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("invalid error: %v", err)
+	}
+}
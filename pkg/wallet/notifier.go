@@ -0,0 +1,135 @@
+package wallet
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+// Notifier delivers messages through a messenger in the background. A
+// failed delivery is retried with an exponential backoff, and a message
+// is dropped when the queue is full, so Notify never blocks.
+type Notifier struct {
+	messenger types.Messenger
+	queue     chan string
+	attempts  int
+	backoff   time.Duration
+	sleep     func(d time.Duration)
+
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type NotifierOption func(n *Notifier)
+
+// WithRetries makes up to attempts deliveries of a message, waiting
+// backoff after the first failure and twice as long after each next one.
+func WithRetries(attempts int, backoff time.Duration) NotifierOption {
+	return func(n *Notifier) {
+		n.attempts = attempts
+		n.backoff = backoff
+	}
+}
+
+func WithQueueSize(size int) NotifierOption {
+	return func(n *Notifier) {
+		n.queue = make(chan string, size)
+	}
+}
+
+func NewNotifier(messenger types.Messenger, options ...NotifierOption) *Notifier {
+	n := &Notifier{
+		messenger: messenger,
+		queue:     make(chan string, 100),
+		attempts:  5,
+		backoff:   time.Second,
+		sleep:     time.Sleep,
+	}
+	for _, option := range options {
+		option(n)
+	}
+
+	n.wg.Add(1)
+	go n.run()
+
+	return n
+}
+
+// Notify queues the message and reports whether it was queued.
+func (n *Notifier) Notify(message string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return false
+	}
+
+	select {
+	case n.queue <- message:
+		return true
+	default:
+		log.Printf("notifier: queue is full, message dropped: %s", message)
+		return false
+	}
+}
+
+// Close stops accepting messages and waits until the queued ones are
+// delivered or given up on.
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	n.wg.Wait()
+}
+
+func (n *Notifier) run() {
+	defer n.wg.Done()
+
+	for message := range n.queue {
+		n.deliver(message)
+	}
+}
+
+func (n *Notifier) deliver(message string) {
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		if n.messenger.Send(message) {
+			return
+		}
+
+		if attempt >= n.attempts {
+			log.Printf("notifier: message not delivered after %d attempts: %s", attempt, message)
+			return
+		}
+
+		n.sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// notify formats and queues a message when the Service has a notifier.
+func (s *Service) notify(format string, args ...interface{}) {
+	if s.notifier == nil {
+		return
+	}
+
+	s.notifier.Notify(fmt.Sprintf(format, args...))
+}
+
+// notifyLowBalance tells when the balance of the account drops below the
+// low balance threshold.
+func (s *Service) notifyLowBalance(account *types.Account, before types.Money) {
+	if s.lowBalance == 0 || before < s.lowBalance || account.Balance >= s.lowBalance {
+		return
+	}
+
+	s.notify("Account %d: low balance %d %s", account.ID, account.Balance, account.Currency)
+}
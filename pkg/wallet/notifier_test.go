@@ -0,0 +1,217 @@
+package wallet
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMessenger struct {
+	mu       sync.Mutex
+	failures int
+	sends    int
+	messages []string
+	release  chan struct{}
+}
+
+func (m *testMessenger) Send(message string) bool {
+	if m.release != nil {
+		<-m.release
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sends++
+	if m.failures != 0 {
+		m.failures--
+		return false
+	}
+
+	m.messages = append(m.messages, message)
+	return true
+}
+
+func (m *testMessenger) Receive() (string, bool) {
+	return "", false
+}
+
+func newTestNotifier(messenger *testMessenger, sleeps *[]time.Duration, options ...NotifierOption) *Notifier {
+	n := NewNotifier(messenger, options...)
+	n.sleep = func(d time.Duration) {
+		*sleeps = append(*sleeps, d)
+	}
+
+	return n
+}
+
+func TestNotifier_retry(t *testing.T) {
+	messenger := &testMessenger{failures: 2, release: make(chan struct{})}
+	var sleeps []time.Duration
+	n := newTestNotifier(messenger, &sleeps, WithRetries(5, time.Second))
+
+	n.Notify("hello")
+	close(messenger.release)
+	n.Close()
+
+	if !reflect.DeepEqual(messenger.messages, []string{"hello"}) || messenger.sends != 3 {
+		t.Errorf("invalid delivery: %v after %v sends", messenger.messages, messenger.sends)
+	}
+
+	if !reflect.DeepEqual(sleeps, []time.Duration{time.Second, 2 * time.Second}) {
+		t.Errorf("invalid backoff: %v", sleeps)
+	}
+
+	if n.Notify("after close") {
+		t.Error("message queued after close")
+	}
+}
+
+func TestNotifier_giveUp(t *testing.T) {
+	messenger := &testMessenger{failures: 10, release: make(chan struct{})}
+	var sleeps []time.Duration
+	n := newTestNotifier(messenger, &sleeps, WithRetries(3, time.Millisecond))
+
+	n.Notify("hello")
+	close(messenger.release)
+	n.Close()
+
+	if len(messenger.messages) != 0 || messenger.sends != 3 || len(sleeps) != 2 {
+		t.Errorf("invalid delivery: %v after %v sends, sleeps - %v", messenger.messages, messenger.sends, sleeps)
+	}
+}
+
+func TestService_notifications(t *testing.T) {
+	messenger := &testMessenger{}
+	n := NewNotifier(messenger)
+	s := &testService{Service: NewService(NewMemoryRepository(), WithNotifier(n), WithLowBalance(500))}
+
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment, err := s.Pay(account.ID, 600, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(account.ID, 100, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	n.Close()
+
+	want := []string{
+		"Account 1: deposit 1000 TJS, balance 1000 TJS",
+		"Account 1: payment 600 TJS for auto, balance 400 TJS",
+		"Account 1: low balance 400 TJS",
+		"Account 1: payment 100 TJS for auto, balance 300 TJS",
+		"Account 1: payment " + payment.ID + " rejected, 600 TJS returned, balance 900 TJS",
+	}
+	if !reflect.DeepEqual(messenger.messages, want) {
+		t.Errorf("invalid messages:\n%v\nwant:\n%v", strings.Join(messenger.messages, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestService_notifications_transfer(t *testing.T) {
+	messenger := &testMessenger{}
+	n := NewNotifier(messenger)
+	s := &testService{Service: NewService(NewMemoryRepository(), WithNotifier(n))}
+
+	from, to, err := s.addTransferAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	refunded, err := s.Transfer(from.ID, to.ID, 300)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Refund(refunded.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	rejected, err := s.Transfer(from.ID, to.ID, 200)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(rejected.LinkedID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	n.Close()
+
+	want := []string{
+		"Account 1: deposit 1000 TJS, balance 1000 TJS",
+		"Account 1: transfer 300 TJS to account 2, balance 700 TJS",
+		"Account 2: transfer 300 TJS from account 1, balance 300 TJS",
+		"Account 1: transfer " + refunded.ID + " refunded, 100 TJS returned, balance 800 TJS",
+		"Account 2: transfer " + refunded.LinkedID + " refunded, 100 TJS taken back, balance 200 TJS",
+		"Account 1: transfer 200 TJS to account 2, balance 600 TJS",
+		"Account 2: transfer 200 TJS from account 1, balance 400 TJS",
+		"Account 1: transfer " + rejected.ID + " rejected, 200 TJS returned, balance 800 TJS",
+		"Account 2: transfer " + rejected.LinkedID + " rejected, 200 TJS taken back, balance 200 TJS",
+	}
+	if !reflect.DeepEqual(messenger.messages, want) {
+		t.Errorf("invalid messages:\n%v\nwant:\n%v", strings.Join(messenger.messages, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestService_Pay_slowMessenger(t *testing.T) {
+	messenger := &testMessenger{release: make(chan struct{})}
+	n := NewNotifier(messenger, WithQueueSize(1))
+	s := &testService{Service: NewService(NewMemoryRepository(), WithNotifier(n))}
+
+	account, err := s.addAccountWithBalance("+992000000001", 1_000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			_, err := s.Pay(account.ID, 1, "auto")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("payments blocked by the messenger")
+	}
+
+	close(messenger.release)
+	n.Close()
+}
@@ -0,0 +1,115 @@
+package wallet
+
+import "github.com/AlisherGulomzoda/wallet/pkg/types"
+
+const DefaultCurrency = types.CurrencyTJS
+
+type Option func(s *Service)
+
+func WithClock(clock Clock) Option {
+	return func(s *Service) {
+		s.clock = clock
+	}
+}
+
+// WithDefaultCurrency sets the currency of accounts opened by
+// RegisterAccount.
+func WithDefaultCurrency(currency types.Currency) Option {
+	return func(s *Service) {
+		s.currency = currency
+	}
+}
+
+// WithRates enables payments and transfers in a currency other than the
+// currency of the account.
+func WithRates(rates RateProvider) Option {
+	return func(s *Service) {
+		s.rates = rates
+	}
+}
+
+func WithRounding(mode RoundingMode) Option {
+	return func(s *Service) {
+		s.rounding = mode
+	}
+}
+
+// WithNotifier sends messages about deposits, payments, rejects and low
+// balances through the notifier.
+func WithNotifier(notifier *Notifier) Option {
+	return func(s *Service) {
+		s.notifier = notifier
+	}
+}
+
+// WithLowBalance sets the balance below which a payment is followed by a
+// low balance message.
+func WithLowBalance(threshold types.Money) Option {
+	return func(s *Service) {
+		s.lowBalance = threshold
+	}
+}
+
+// WithEventBus publishes the events of every change of the Service to
+// the bus.
+func WithEventBus(bus *EventBus) Option {
+	return func(s *Service) {
+		s.events = bus
+	}
+}
+
+// PayOption changes a single money-moving call of the Service.
+type PayOption func(o *payOptions)
+
+type payOptions struct {
+	currency types.Currency
+	key      string
+}
+
+// WithCurrency sets the currency of the amount. By default the amount is
+// in the currency of the account.
+func WithCurrency(currency types.Currency) PayOption {
+	return func(o *payOptions) {
+		o.currency = currency
+	}
+}
+
+// WithIdempotencyKey makes a retry of the call with the same key return
+// the result of the first call instead of moving money again.
+func WithIdempotencyKey(key string) PayOption {
+	return func(o *payOptions) {
+		o.key = key
+	}
+}
+
+// PayOptions returns the options for a call with the currency and the
+// idempotency key, skipping the empty ones.
+func PayOptions(currency types.Currency, key string) []PayOption {
+	options := []PayOption{}
+	if currency != "" {
+		options = append(options, WithCurrency(currency))
+	}
+
+	if key != "" {
+		options = append(options, WithIdempotencyKey(key))
+	}
+
+	return options
+}
+
+func newPayOptions(options []PayOption) *payOptions {
+	o := &payOptions{}
+	for _, option := range options {
+		option(o)
+	}
+
+	return o
+}
+
+func (s *Service) defaultCurrency() types.Currency {
+	if s.currency == "" {
+		return DefaultCurrency
+	}
+
+	return s.currency
+}
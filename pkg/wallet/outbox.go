@@ -0,0 +1,248 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var ErrNotDelivered = errors.New("event not delivered")
+
+// OutboxEntry is an event saved in the outbox together with the change it
+// describes. Seq is the position of the entry in the outbox, from 1.
+type OutboxEntry struct {
+	Seq   int64
+	Event Event
+}
+
+// Sink receives events from a Relay. An event may be delivered more than
+// once, the ID of the event tells the copies apart.
+type Sink interface {
+	Deliver(entry OutboxEntry) error
+}
+
+type SinkFunc func(entry OutboxEntry) error
+
+func (f SinkFunc) Deliver(entry OutboxEntry) error {
+	return f(entry)
+}
+
+// MessengerSink sends every event as its name followed by its JSON.
+func MessengerSink(messenger types.Messenger) Sink {
+	return SinkFunc(func(entry OutboxEntry) error {
+		message, err := formatEntry(entry)
+		if err != nil {
+			return err
+		}
+
+		if !messenger.Send(message) {
+			return ErrNotDelivered
+		}
+
+		return nil
+	})
+}
+
+// WriterSink writes every event to w as a line of its seq, its name and
+// its JSON.
+func WriterSink(w io.Writer) Sink {
+	return SinkFunc(func(entry OutboxEntry) error {
+		message, err := formatEntry(entry)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%d %s\n", entry.Seq, message)
+		return err
+	})
+}
+
+func formatEntry(entry OutboxEntry) (string, error) {
+	data, err := json.Marshal(entry.Event)
+	if err != nil {
+		return "", err
+	}
+
+	return entry.Event.EventName() + " " + string(data), nil
+}
+
+// DedupSink passes an event to the sink only once, remembering the IDs of
+// up to size last delivered events.
+func DedupSink(sink Sink, size int) Sink {
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	order := make([]string, 0, size)
+
+	return SinkFunc(func(entry OutboxEntry) error {
+		id := entry.Event.Header().ID
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if seen[id] {
+			return nil
+		}
+
+		err := sink.Deliver(entry)
+		if err != nil {
+			return err
+		}
+
+		seen[id] = true
+		order = append(order, id)
+		if len(order) > size {
+			delete(seen, order[0])
+			order = order[1:]
+		}
+
+		return nil
+	})
+}
+
+// Relay delivers the events of the outbox to a sink in order. The
+// position of the relay is saved in the repository under its name after
+// every delivered event, so a restarted relay goes on from there.
+type Relay struct {
+	name       string
+	repository Repository
+	sink       Sink
+	batchSize  int
+	attempts   int
+	backoff    time.Duration
+	sleep      func(d time.Duration)
+
+	mu sync.Mutex
+}
+
+type RelayOption func(r *Relay)
+
+// WithRelayRetries makes up to attempts deliveries of an event, waiting
+// backoff after the first failure and twice as long after each next one.
+func WithRelayRetries(attempts int, backoff time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.attempts = attempts
+		r.backoff = backoff
+	}
+}
+
+func WithBatchSize(size int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+func NewRelay(name string, repository Repository, sink Sink, options ...RelayOption) *Relay {
+	r := &Relay{
+		name:       name,
+		repository: repository,
+		sink:       sink,
+		batchSize:  100,
+		attempts:   5,
+		backoff:    time.Second,
+		sleep:      time.Sleep,
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	return r
+}
+
+// NewRelay returns a relay of the outbox of the Service.
+func (s *Service) NewRelay(name string, sink Sink, options ...RelayOption) *Relay {
+	return NewRelay(name, s.repo(), sink, options...)
+}
+
+// Drain delivers all pending events and returns how many were delivered.
+// It stops at the first event which could not be delivered.
+func (r *Relay) Drain() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cursor, err := r.repository.OutboxCursor(r.name)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for {
+		entries, err := r.repository.OutboxEntries(cursor, r.batchSize)
+		if err != nil {
+			return delivered, err
+		}
+
+		if len(entries) == 0 {
+			return delivered, nil
+		}
+
+		for _, entry := range entries {
+			err = r.deliver(entry)
+			if err != nil {
+				return delivered, err
+			}
+
+			cursor = entry.Seq
+			err = r.repository.SaveOutboxCursor(r.name, cursor)
+			if err != nil {
+				return delivered, err
+			}
+			delivered++
+		}
+	}
+}
+
+// Replay delivers again all events starting from the seq. Events already
+// trimmed from the outbox are gone, the replay starts from the oldest
+// kept one then.
+func (r *Relay) Replay(seq int64) (int, error) {
+	if seq < 1 {
+		seq = 1
+	}
+
+	r.mu.Lock()
+	err := r.repository.SaveOutboxCursor(r.name, seq-1)
+	r.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	return r.Drain()
+}
+
+// Run drains the outbox every interval until stop is closed.
+func (r *Relay) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := r.Drain()
+		if err != nil {
+			log.Printf("relay %s: %v", r.name, err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) deliver(entry OutboxEntry) error {
+	backoff := r.backoff
+	for attempt := 1; ; attempt++ {
+		err := r.sink.Deliver(entry)
+		if err == nil || attempt >= r.attempts {
+			return err
+		}
+
+		r.sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,260 @@
+package wallet
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSink struct {
+	failures map[int64]int
+	entries  []OutboxEntry
+}
+
+func (s *testSink) Deliver(entry OutboxEntry) error {
+	if s.failures[entry.Seq] != 0 {
+		s.failures[entry.Seq]--
+		return ErrNotDelivered
+	}
+
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *testSink) seqs() []int64 {
+	seqs := make([]int64, len(s.entries))
+	for i, entry := range s.entries {
+		seqs[i] = entry.Seq
+	}
+
+	return seqs
+}
+
+func newTestRelay(s *testService, sink Sink, sleeps *[]time.Duration) *Relay {
+	relay := s.NewRelay("test", sink, WithRelayRetries(3, time.Second), WithBatchSize(2))
+	relay.sleep = func(d time.Duration) {
+		*sleeps = append(*sleeps, d)
+	}
+
+	return relay
+}
+
+func TestRelay_Drain_success(t *testing.T) {
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	sink := &testSink{failures: map[int64]int{2: 2}}
+	var sleeps []time.Duration
+	relay := newTestRelay(s, sink, &sleeps)
+
+	delivered, err := relay.Drain()
+	if err != nil || delivered != 3 {
+		t.Errorf("invalid drain: %v, %v", delivered, err)
+		return
+	}
+
+	if !reflect.DeepEqual(sink.seqs(), []int64{1, 2, 3}) {
+		t.Errorf("invalid delivered events: %v", sink.seqs())
+	}
+
+	names := []string{}
+	for _, entry := range sink.entries {
+		names = append(names, entry.Event.EventName())
+	}
+	if !reflect.DeepEqual(names, []string{"AccountRegistered", "Deposited", "PaymentCreated"}) {
+		t.Errorf("invalid delivered events: %v", names)
+	}
+
+	if !reflect.DeepEqual(sleeps, []time.Duration{time.Second, 2 * time.Second}) {
+		t.Errorf("invalid backoff: %v", sleeps)
+	}
+
+	delivered, err = relay.Drain()
+	if err != nil || delivered != 0 {
+		t.Errorf("events delivered twice: %v, %v", delivered, err)
+	}
+}
+
+func TestRelay_Drain_fail(t *testing.T) {
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	sink := &testSink{failures: map[int64]int{2: 3}}
+	var sleeps []time.Duration
+	relay := newTestRelay(s, sink, &sleeps)
+
+	delivered, err := relay.Drain()
+	if !errors.Is(err, ErrNotDelivered) || delivered != 1 {
+		t.Errorf("invalid drain: %v, %v", delivered, err)
+		return
+	}
+
+	cursor, err := s.repo().OutboxCursor("test")
+	if err != nil || cursor != 1 {
+		t.Errorf("invalid cursor: %v, %v", cursor, err)
+		return
+	}
+
+	restarted := newTestRelay(s, sink, &sleeps)
+	delivered, err = restarted.Drain()
+	if err != nil || delivered != 2 || !reflect.DeepEqual(sink.seqs(), []int64{1, 2, 3}) {
+		t.Errorf("invalid drain after restart: %v, %v, %v", delivered, err, sink.seqs())
+	}
+}
+
+func TestRelay_Replay(t *testing.T) {
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	sink := &testSink{}
+	var sleeps []time.Duration
+	relay := newTestRelay(s, sink, &sleeps)
+
+	_, err = relay.Drain()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	delivered, err := relay.Replay(2)
+	if err != nil || delivered != 2 || !reflect.DeepEqual(sink.seqs(), []int64{1, 2, 3, 2, 3}) {
+		t.Errorf("invalid replay: %v, %v, %v", delivered, err, sink.seqs())
+		return
+	}
+
+	dedup := &testSink{}
+	relay = newTestRelay(s, DedupSink(dedup, 10), &sleeps)
+	_, err = relay.Drain()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = relay.Replay(1)
+	if err != nil || !reflect.DeepEqual(dedup.seqs(), []int64{1, 2, 3}) {
+		t.Errorf("duplicates not dropped: %v, %v", err, dedup.seqs())
+	}
+}
+
+func TestMessengerSink(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	messenger := &testMessenger{failures: 1}
+	relay := s.NewRelay("messenger", MessengerSink(messenger), WithRelayRetries(2, 0))
+
+	delivered, err := relay.Drain()
+	if err != nil || delivered != 1 || len(messenger.messages) != 1 {
+		t.Errorf("invalid drain: %v, %v, %v", delivered, err, messenger.messages)
+		return
+	}
+
+	entries, err := s.repo().OutboxEntries(0, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	message := messenger.messages[0]
+	if !strings.HasPrefix(message, "AccountRegistered {") || !strings.Contains(message, `"ID":"`+entries[0].Event.Header().ID+`"`) || !strings.Contains(message, string(account.Phone)) {
+		t.Errorf("invalid message: %v", message)
+	}
+}
+
+func outboxSeqs(t *testing.T, repo Repository, after int64) []int64 {
+	entries, err := repo.OutboxEntries(after, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seqs := make([]int64, len(entries))
+	for i, entry := range entries {
+		seqs[i] = entry.Seq
+	}
+
+	return seqs
+}
+
+func TestMemoryRepository_trimOutbox(t *testing.T) {
+	repo := NewMemoryRepository()
+	repo.outboxRetention = 2
+	for i := 0; i < 10; i++ {
+		err := repo.Save(&Batch{Events: []Event{&AccountRegistered{}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := repo.SaveOutboxCursor("slow", 3)
+	if err == nil {
+		err = repo.SaveOutboxCursor("fast", 9)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seqs := outboxSeqs(t, repo, 0); len(seqs) != 10 {
+		t.Errorf("entries trimmed before the slow relay passed them: %v", seqs)
+		return
+	}
+
+	err = repo.SaveOutboxCursor("slow", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seqs := outboxSeqs(t, repo, 0); !reflect.DeepEqual(seqs, []int64{7, 8, 9, 10}) {
+		t.Errorf("invalid kept entries: %v", seqs)
+	}
+
+	if seqs := outboxSeqs(t, repo, 8); !reflect.DeepEqual(seqs, []int64{9, 10}) {
+		t.Errorf("invalid entries after a cursor: %v", seqs)
+	}
+
+	err = repo.Save(&Batch{Events: []Event{&AccountRegistered{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seqs := outboxSeqs(t, repo, 10); !reflect.DeepEqual(seqs, []int64{11}) {
+		t.Errorf("invalid seq of a new entry: %v", seqs)
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	s := newTestService()
+	_, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	buf := &strings.Builder{}
+	delivered, err := s.NewRelay("log", WriterSink(buf)).Drain()
+	if err != nil || delivered != 1 {
+		t.Errorf("invalid drain: %v, %v", delivered, err)
+		return
+	}
+
+	if !strings.HasPrefix(buf.String(), "1 AccountRegistered {") || !strings.HasSuffix(buf.String(), "}\n") {
+		t.Errorf("invalid line: %q", buf.String())
+	}
+}
@@ -0,0 +1,60 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var ErrOverdraftInUse = errors.New("overdraft is less than the used credit")
+
+// SetOverdraft lets the balance of the account go below zero down to
+// minus the limit. The limit can't be set below the credit already used.
+func (s *Service) SetOverdraft(accountID int64, limit types.Money) error {
+	if limit < 0 {
+		return ErrInvalidLimit
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, err := s.repo().FindAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	if creditUsed(account) > limit {
+		return ErrOverdraftInUse
+	}
+
+	account.Overdraft = limit
+
+	return s.save(&Batch{
+		Accounts: []*types.Account{account},
+		Events: []Event{&OverdraftChanged{
+			EventHeader: EventHeader{AccountID: account.ID},
+			Overdraft:   limit,
+		}},
+	})
+}
+
+// CreditUsed returns how much of the overdraft of the account is used.
+func (s *Service) CreditUsed(accountID int64) (types.Money, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account, err := s.repo().FindAccountByID(accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	return creditUsed(account), nil
+}
+
+func creditUsed(account *types.Account) types.Money {
+	if account.Balance >= 0 {
+		return 0
+	}
+
+	return -account.Balance
+}
@@ -0,0 +1,140 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestService_Pay_overdraft(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992000000001", 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(account.ID, 150, "auto")
+	if err != ErrBalanceNotAmount {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetOverdraft(account.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment, err := s.Pay(account.ID, 150, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	used, err := s.CreditUsed(account.ID)
+	if err != nil || used != 50 || s.balance(account.ID) != -50 {
+		t.Errorf("invalid credit used: %v, %v, balance - %v", used, err, s.balance(account.ID))
+		return
+	}
+
+	_, err = s.Pay(account.ID, 51, "auto")
+	if err != ErrBalanceNotAmount {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetOverdraft(account.ID, 40)
+	if err != ErrOverdraftInUse {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	used, err = s.CreditUsed(account.ID)
+	if err != nil || used != 0 {
+		t.Errorf("invalid credit used after reject: %v, %v", used, err)
+		return
+	}
+
+	mismatches, err := s.Reconcile()
+	if err != nil || len(mismatches) != 0 {
+		t.Errorf("ledger does not match balances: %v, %v", mismatches, err)
+	}
+}
+
+func TestService_SetOverdraft_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetOverdraft(account.ID, -1)
+	if err != ErrInvalidLimit {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetOverdraft(account.ID+1, 100)
+	if err != ErrAccountNotFound {
+		t.Error(err)
+	}
+}
+
+func TestService_Import_overdraft(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.SetOverdraft(account.ID, 500)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(account.ID, 200, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	got, err := imported.FindAccountByID(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got.Overdraft != 500 || got.Balance != -200 {
+		t.Errorf("invalid account: %v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// PhoneError tells why a phone number is invalid. It matches
+// ErrInvalidPhone with errors.Is.
+type PhoneError struct {
+	Phone  types.Phone
+	Reason string
+}
+
+func (e *PhoneError) Error() string {
+	return fmt.Sprintf("invalid phone number %q: %s", e.Phone, e.Reason)
+}
+
+func (e *PhoneError) Is(target error) bool {
+	return target == ErrInvalidPhone
+}
+
+// phoneDigits is the number of digits after the country code of a phone
+// number in the country.
+var phoneDigits = map[string]int{
+	"1":   10, // USA, Canada
+	"7":   10, // Russia, Kazakhstan
+	"44":  10, // United Kingdom
+	"86":  11, // China
+	"90":  10, // Turkey
+	"971": 9,  // United Arab Emirates
+	"992": 9,  // Tajikistan
+	"993": 8,  // Turkmenistan
+	"996": 9,  // Kyrgyzstan
+	"998": 9,  // Uzbekistan
+}
+
+// NormalizePhone returns the phone number in the E.164 format, like
+// "+992900000000". The number must start with the country code, either
+// after "+" or "00" or as is, and may contain spaces, dashes, dots and
+// parentheses. Numbers of the countries in phoneDigits must have the
+// length of that country, numbers of other countries must fit E.164.
+func NormalizePhone(phone types.Phone) (types.Phone, error) {
+	invalid := func(reason string) (types.Phone, error) {
+		return "", &PhoneError{Phone: phone, Reason: reason}
+	}
+
+	digits := strings.TrimSpace(string(phone))
+	digits = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(digits)
+	if strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+	} else if strings.HasPrefix(digits, "00") {
+		digits = digits[2:]
+	}
+
+	if digits == "" {
+		return invalid("no digits")
+	}
+
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return invalid(fmt.Sprintf("unexpected character %q", r))
+		}
+	}
+
+	if digits[0] == '0' {
+		return invalid("country code can't start with 0")
+	}
+
+	if len(digits) > 15 {
+		return invalid("more than 15 digits")
+	}
+
+	for i := 3; i > 0; i-- {
+		if i > len(digits) {
+			continue
+		}
+
+		want, ok := phoneDigits[digits[:i]]
+		if !ok {
+			continue
+		}
+
+		if len(digits)-i != want {
+			return invalid(fmt.Sprintf("+%s numbers have %d digits after the country code, got %d", digits[:i], want, len(digits)-i))
+		}
+
+		return types.Phone("+" + digits), nil
+	}
+
+	if len(digits) < 8 {
+		return invalid("less than 8 digits")
+	}
+
+	return types.Phone("+" + digits), nil
+}
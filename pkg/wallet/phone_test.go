@@ -0,0 +1,78 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func TestNormalizePhone_success(t *testing.T) {
+	tests := []struct {
+		phone types.Phone
+		want  types.Phone
+	}{
+		{"+992900000000", "+992900000000"},
+		{"+992 900 00 00 00", "+992900000000"},
+		{"992900000000", "+992900000000"},
+		{"00992900000000", "+992900000000"},
+		{"+992 (90) 000-00.00", "+992900000000"},
+		{"+992000000001", "+992000000001"},
+		{"+7 701 000 00 00", "+77010000000"},
+		{"+1 (202) 555-0100", "+12025550100"},
+		{"+33 1 23 45 67 89", "+33123456789"},
+	}
+
+	for _, test := range tests {
+		got, err := NormalizePhone(test.phone)
+		if err != nil || got != test.want {
+			t.Errorf("NormalizePhone(%q) = %q, %v; want %q", test.phone, got, err, test.want)
+		}
+	}
+}
+
+func TestNormalizePhone_fail(t *testing.T) {
+	tests := []types.Phone{
+		"",
+		"+",
+		"+99290000000",
+		"+9929000000000",
+		"+992 90O 00 00 00",
+		"+0992900000000",
+		"+3312345",
+		"+1234567890123456",
+		"12",
+	}
+
+	for _, phone := range tests {
+		_, err := NormalizePhone(phone)
+		var phoneError *PhoneError
+		if !errors.As(err, &phoneError) || !errors.Is(err, ErrInvalidPhone) || phoneError.Phone != phone {
+			t.Errorf("NormalizePhone(%q): invalid error %v", phone, err)
+		}
+	}
+}
+
+func TestService_RegisterAccount_normalizedPhone(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992 900 00 00 00")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if account.Phone != "+992900000000" {
+		t.Errorf("phone not normalized: %v", account.Phone)
+	}
+
+	_, err = s.RegisterAccount("992900000000")
+	if err != ErrPhoneAlreadyRegitered {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.RegisterAccount("900000000")
+	if !errors.Is(err, ErrInvalidPhone) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,176 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var (
+	ErrRateNotFound = errors.New("exchange rate not found")
+	ErrInvalidRate  = errors.New("invalid exchange rate")
+)
+
+// ExchangeAccountID is the ledger account through which money changes
+// its currency.
+const ExchangeAccountID int64 = -1
+
+// RateProvider tells how many minor units of the to currency one minor
+// unit of the from currency costs.
+type RateProvider interface {
+	Rate(from, to types.Currency) (types.Rate, error)
+}
+
+// StaticRates is a fixed table of rates. When only the opposite rate is
+// known, the inverse of it is used.
+type StaticRates map[types.Currency]map[types.Currency]types.Rate
+
+func (r StaticRates) Rate(from, to types.Currency) (types.Rate, error) {
+	if from == to {
+		return types.RateScale, nil
+	}
+
+	if rate, ok := r[from][to]; ok {
+		return rate, nil
+	}
+
+	if rate, ok := r[to][from]; ok && rate > 0 {
+		return types.Rate(scale(types.Money(types.RateScale), types.Money(types.RateScale), types.Money(rate), RoundHalfUp)), nil
+	}
+
+	return 0, ErrRateNotFound
+}
+
+func (r StaticRates) Set(from, to types.Currency, rate types.Rate) {
+	if r[from] == nil {
+		r[from] = make(map[types.Currency]types.Rate)
+	}
+
+	r[from][to] = rate
+}
+
+// LoadRates reads rates from a file with lines like "USD;TJS;10.95".
+func LoadRates(path string) (StaticRates, error) {
+	byteData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := StaticRates{}
+	for _, line := range strings.Split(string(byteData), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		data := strings.Split(line, ";")
+		if len(data) != 3 {
+			return nil, ErrInvalidRate
+		}
+
+		rate, err := ParseRate(data[2])
+		if err != nil {
+			return nil, err
+		}
+
+		rates.Set(types.Currency(data[0]), types.Currency(data[1]), rate)
+	}
+
+	return rates, nil
+}
+
+// ParseRate parses a decimal rate with up to six fractional digits.
+func ParseRate(value string) (types.Rate, error) {
+	whole, fraction := value, ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		whole, fraction = value[:i], value[i+1:]
+	}
+
+	digits := len(strconv.Itoa(int(types.RateScale))) - 1
+	if whole == "" || len(fraction) > digits {
+		return 0, ErrInvalidRate
+	}
+
+	rate, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", digits-len(fraction)), 10, 64)
+	if err != nil || rate <= 0 {
+		return 0, ErrInvalidRate
+	}
+
+	return types.Rate(rate), nil
+}
+
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds halves away from zero. It is the default.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds halves to the nearest even minor unit.
+	RoundHalfEven
+	// RoundDown truncates towards zero.
+	RoundDown
+	// RoundUp rounds away from zero.
+	RoundUp
+)
+
+// Convert converts the amount by the rate and rounds the result to a
+// whole minor unit with the mode.
+func Convert(amount types.Money, rate types.Rate, mode RoundingMode) types.Money {
+	return scale(amount, types.Money(rate), types.Money(types.RateScale), mode)
+}
+
+// scale returns amount * num / den rounded with the mode.
+func scale(amount, num, den types.Money, mode RoundingMode) types.Money {
+	product := new(big.Int).Mul(big.NewInt(int64(amount)), big.NewInt(int64(num)))
+	divisor := big.NewInt(int64(den))
+	quotient, remainder := new(big.Int).QuoRem(product, divisor, new(big.Int))
+	if remainder.Sign() == 0 {
+		return types.Money(quotient.Int64())
+	}
+
+	away := false
+	switch mode {
+	case RoundUp:
+		away = true
+	case RoundHalfUp, RoundHalfEven:
+		twice := new(big.Int).Abs(new(big.Int).Mul(remainder, big.NewInt(2)))
+		switch twice.Cmp(new(big.Int).Abs(divisor)) {
+		case 1:
+			away = true
+		case 0:
+			away = mode == RoundHalfUp || quotient.Bit(0) == 1
+		}
+	}
+
+	if away {
+		if product.Sign()*divisor.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	return types.Money(quotient.Int64())
+}
+
+// convert returns the cash in the currency together with the rate used.
+// Cash already in the currency is returned as is with a zero rate.
+func (s *Service) convert(cash types.Cash, currency types.Currency) (types.Cash, types.Rate, error) {
+	if cash.Currency == currency {
+		return cash, 0, nil
+	}
+
+	if s.rates == nil {
+		return types.Cash{}, 0, types.ErrCurrencyMismatch
+	}
+
+	rate, err := s.rates.Rate(cash.Currency, currency)
+	if err != nil {
+		return types.Cash{}, 0, err
+	}
+
+	return types.Cash{Amount: Convert(cash.Amount, rate, s.rounding), Currency: currency}, rate, nil
+}
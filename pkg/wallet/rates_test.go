@@ -0,0 +1,297 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func newRatesTestService() *testService {
+	rates := StaticRates{}
+	rates.Set(types.CurrencyUSD, types.CurrencyTJS, 10_950_000)
+
+	s := newTestService()
+	s.rates = rates
+	return s
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  types.Rate
+		err   error
+	}{
+		{"10.95", 10_950_000, nil},
+		{"1", 1_000_000, nil},
+		{"0.000001", 1, nil},
+		{"0", 0, ErrInvalidRate},
+		{"-1.5", 0, ErrInvalidRate},
+		{"1.0000001", 0, ErrInvalidRate},
+		{".5", 0, ErrInvalidRate},
+		{"abc", 0, ErrInvalidRate},
+	}
+
+	for _, test := range tests {
+		got, err := ParseRate(test.value)
+		if err != test.err || got != test.want {
+			t.Errorf("ParseRate(%q) = %v, %v; want %v, %v", test.value, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestConvert_rounding(t *testing.T) {
+	tests := []struct {
+		amount types.Money
+		mode   RoundingMode
+		want   types.Money
+	}{
+		{5, RoundHalfUp, 3},
+		{5, RoundHalfEven, 2},
+		{7, RoundHalfEven, 4},
+		{5, RoundDown, 2},
+		{5, RoundUp, 3},
+		{-5, RoundHalfUp, -3},
+		{-5, RoundHalfEven, -2},
+		{-5, RoundDown, -2},
+		{-5, RoundUp, -3},
+		{4, RoundUp, 2},
+	}
+
+	for _, test := range tests {
+		got := Convert(test.amount, types.RateScale/2, test.mode)
+		if got != test.want {
+			t.Errorf("Convert(%v, 0.5, %v) = %v, want %v", test.amount, test.mode, got, test.want)
+		}
+	}
+}
+
+func TestStaticRates_inverse(t *testing.T) {
+	rates := StaticRates{}
+	rates.Set(types.CurrencyUSD, types.CurrencyTJS, 10_000_000)
+
+	rate, err := rates.Rate(types.CurrencyTJS, types.CurrencyUSD)
+	if err != nil || rate != 100_000 {
+		t.Errorf("invalid inverse rate: %v, %v", rate, err)
+	}
+
+	rate, err = rates.Rate(types.CurrencyEUR, types.CurrencyEUR)
+	if err != nil || rate != types.RateScale {
+		t.Errorf("invalid same currency rate: %v, %v", rate, err)
+	}
+
+	_, err = rates.Rate(types.CurrencyEUR, types.CurrencyTJS)
+	if err != ErrRateNotFound {
+		t.Error(err)
+	}
+}
+
+func TestLoadRates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rates.dump")
+	err = ioutil.WriteFile(path, []byte("USD;TJS;10.95\nEUR;TJS;11.8\n"), 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	rates, err := LoadRates(path)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if rates[types.CurrencyUSD][types.CurrencyTJS] != 10_950_000 || rates[types.CurrencyEUR][types.CurrencyTJS] != 11_800_000 {
+		t.Errorf("invalid rates: %v", rates)
+	}
+
+	err = ioutil.WriteFile(path, []byte("USD;TJS\n"), 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = LoadRates(path)
+	if err != ErrInvalidRate {
+		t.Error(err)
+	}
+}
+
+func TestService_Pay_convert(t *testing.T) {
+	s := newRatesTestService()
+	tjs, _, err := s.addCurrencyAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment, err := s.Pay(tjs.ID, 10, "auto", WithCurrency(types.CurrencyUSD))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if payment.Amount != 110 || payment.Currency != types.CurrencyTJS {
+		t.Errorf("invalid charged amount: %v", payment)
+	}
+
+	if payment.OriginalAmount != 10 || payment.OriginalCurrency != types.CurrencyUSD || payment.Rate != 10_950_000 {
+		t.Errorf("invalid original amount: %v", payment)
+	}
+
+	if s.balance(tjs.ID) != 890 {
+		t.Errorf("invalid balance: %v", s.balance(tjs.ID))
+	}
+
+	repeated, err := s.Repeat(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if repeated.OriginalAmount != 10 || repeated.OriginalCurrency != types.CurrencyUSD {
+		t.Errorf("invalid repeated payment: %v", repeated)
+	}
+}
+
+func TestService_Deposit_convertToZero(t *testing.T) {
+	s := newTestService()
+	rates := StaticRates{}
+	rates.Set(types.CurrencyUSD, types.CurrencyTJS, 300_000)
+	s.rates = rates
+
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Deposit(account.ID, 1, WithCurrency(types.CurrencyUSD))
+	if err != ErrAmountMustGreateZero {
+		t.Errorf("invalid error: %v", err)
+		return
+	}
+
+	postings, err := s.repo().Postings()
+	if err != nil || len(postings) != 0 {
+		t.Errorf("posting of a rejected deposit: %v, %v", postings, err)
+	}
+}
+
+func TestService_Refund_transferPartRoundsToZero(t *testing.T) {
+	s := newTestService()
+	rates := StaticRates{}
+	rates.Set(types.CurrencyUSD, types.CurrencyTJS, 300_000)
+	s.rates = rates
+
+	tjs, usd, err := s.addCurrencyAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	outgoing, err := s.Transfer(usd.ID, tjs.ID, 3)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s.balance(usd.ID) != 97 || s.balance(tjs.ID) != 1_001 {
+		t.Errorf("invalid balances: usd - %v, tjs - %v", s.balance(usd.ID), s.balance(tjs.ID))
+		return
+	}
+
+	err = s.Refund(outgoing.ID, 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s.balance(usd.ID) != 98 || s.balance(tjs.ID) != 1_001 {
+		t.Errorf("invalid balances after refund: usd - %v, tjs - %v", s.balance(usd.ID), s.balance(tjs.ID))
+		return
+	}
+
+	err = s.Refund(outgoing.ID, 2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s.balance(usd.ID) != 100 || s.balance(tjs.ID) != 1_000 {
+		t.Errorf("invalid balances after the last refund: usd - %v, tjs - %v", s.balance(usd.ID), s.balance(tjs.ID))
+		return
+	}
+
+	mismatches, err := s.Reconcile()
+	if err != nil || len(mismatches) != 0 {
+		t.Errorf("ledger does not match balances: %v, %v", mismatches, err)
+	}
+}
+
+func TestService_Transfer_convert(t *testing.T) {
+	s := newRatesTestService()
+	tjs, usd, err := s.addCurrencyAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	outgoing, err := s.Transfer(tjs.ID, usd.ID, 219)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	incoming, err := s.FindPaymetByID(outgoing.LinkedID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if incoming.Amount != 20 || incoming.Currency != types.CurrencyUSD || incoming.Rate == 0 {
+		t.Errorf("invalid incoming payment: %v", incoming)
+	}
+
+	if s.balance(tjs.ID) != 781 || s.balance(usd.ID) != 120 {
+		t.Errorf("invalid balances: tjs - %v, usd - %v", s.balance(tjs.ID), s.balance(usd.ID))
+	}
+
+	err = s.Refund(outgoing.ID, 109)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s.balance(tjs.ID) != 890 || s.balance(usd.ID) != 110 {
+		t.Errorf("invalid balances after refund: tjs - %v, usd - %v", s.balance(tjs.ID), s.balance(usd.ID))
+	}
+
+	err = s.Refund(incoming.ID, 110)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s.balance(tjs.ID) != 1_000 || s.balance(usd.ID) != 100 {
+		t.Errorf("invalid balances after full refund: tjs - %v, usd - %v", s.balance(tjs.ID), s.balance(usd.ID))
+	}
+
+	mismatches, err := s.Reconcile()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(mismatches) != 0 {
+		t.Errorf("ledger does not match balances: %v", mismatches)
+	}
+}
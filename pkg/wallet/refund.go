@@ -0,0 +1,114 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var (
+	ErrRefundExceedsAmount = errors.New("refund exceeds payment amount")
+	ErrPaymentRefunded     = errors.New("payment is refunded, refund the rest instead")
+)
+
+// Refund returns a part of the payment back to the account. Several
+// refunds may be made until the whole amount of the payment is refunded.
+// The amount is in the currency of the account, for a transfer it is in
+// the currency of the sender.
+func (s *Service) Refund(paymentID string, amount types.Money) error {
+	if amount <= 0 {
+		return ErrAmountMustGreateZero
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, err := s.findRefundable(paymentID)
+	if err != nil {
+		return err
+	}
+
+	status := types.PaymentStatusPartiallyRefunded
+	if payment.Refunded+amount == payment.Amount {
+		status = types.PaymentStatusRefunded
+	}
+
+	return s.refund(payment, amount, status, "refund")
+}
+
+// refund returns amount of the payment to its account and moves the
+// payment to the status. The operation names the call in errors.
+func (s *Service) refund(payment *types.Payment, amount types.Money, status types.PaymentStatus, operation string) error {
+	if isTransfer(payment) {
+		return s.refundTransfer(payment, amount, status, operation)
+	}
+
+	_, err := transition(payment, status)
+	if err != nil {
+		return err
+	}
+
+	if amount > payment.Amount-payment.Refunded {
+		return ErrRefundExceedsAmount
+	}
+
+	account, err := s.repo().FindAccountByID(payment.AccountID)
+	if err != nil {
+		return err
+	}
+
+	err = checkActive(account, operation)
+	if err != nil {
+		return err
+	}
+
+	cash := cashOf(account, amount, &payOptions{currency: payment.Currency})
+	err = credit(account, cash)
+	if err != nil {
+		return err
+	}
+
+	payment.Refunded += amount
+
+	err = s.save(&Batch{
+		Accounts: []*types.Account{account},
+		Payments: []*types.Payment{payment},
+		Postings: []*types.Posting{s.newPosting(ExternalAccountID, account.ID, cash, payment.ID)},
+		Events:   []Event{paymentReturned(payment, account, amount, status)},
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notify("Account %d: payment %s %s, %d %s returned, balance %d %s", account.ID, payment.ID, returnAction(status), cash.Amount, cash.Currency, account.Balance, account.Currency)
+
+	return nil
+}
+
+// returnAction names the return of a payment which moves it to the status
+// in notifications.
+func returnAction(status types.PaymentStatus) string {
+	if status == types.PaymentStatusFail {
+		return "rejected"
+	}
+
+	return "refunded"
+}
+
+// findRefundable finds the payment to refund. Refunds of a transfer are
+// always counted on its outgoing side.
+func (s *Service) findRefundable(paymentID string) (*types.Payment, error) {
+	payment, err := s.repo().FindPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTransfer(payment) {
+		payment, _, err = s.findTransfer(payment)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return payment, nil
+}
@@ -0,0 +1,191 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func TestService_Refund_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment := payments[0]
+	steps := []struct {
+		amount types.Money
+		status types.PaymentStatus
+	}{
+		{amount: 100_00, status: types.PaymentStatusPartiallyRefunded},
+		{amount: 400_00, status: types.PaymentStatusPartiallyRefunded},
+		{amount: 500_00, status: types.PaymentStatusRefunded},
+	}
+
+	refunded := types.Money(0)
+	for _, step := range steps {
+		err = s.Refund(payment.ID, step.amount)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		refunded += step.amount
+
+		saved, err := s.FindPaymetByID(payment.ID)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if saved.Status != step.status || saved.Refunded != refunded || saved.Amount != payment.Amount {
+			t.Errorf("invalid payment after refund of %v: %v", step.amount, saved)
+			return
+		}
+	}
+
+	if s.balance(account.ID) != defaultTestAccount.balance {
+		t.Errorf("invalid balance: got %v, want %v", s.balance(account.ID), defaultTestAccount.balance)
+	}
+}
+
+func TestService_Refund_fail(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment := payments[0]
+	err = s.Refund(payment.ID, 0)
+	if err != ErrAmountMustGreateZero {
+		t.Error(err)
+		return
+	}
+
+	err = s.Refund(payment.ID, payment.Amount+1)
+	if err != ErrRefundExceedsAmount {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Refund(payment.ID, 1)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Error(err)
+	}
+}
+
+func TestService_Reject_keepsAmount(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment := payments[0]
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	saved, err := s.FindPaymetByID(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if saved.Amount != payment.Amount || saved.Refunded != payment.Amount {
+		t.Errorf("invalid payment: %v", saved)
+	}
+}
+
+func TestService_Reject_afterRefund(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment := payments[0]
+	err = s.Refund(payment.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	balance := s.balance(account.ID)
+	err = s.Reject(payment.ID)
+	if err != ErrPaymentRefunded {
+		t.Errorf("invalid error: %v", err)
+		return
+	}
+
+	if s.balance(account.ID) != balance {
+		t.Errorf("invalid balance: got - %v, want - %v", s.balance(account.ID), balance)
+		return
+	}
+
+	err = s.Refund(payment.ID, payment.Amount-100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	saved, err := s.FindPaymetByID(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if saved.Status != types.PaymentStatusRefunded || saved.Refunded != payment.Amount {
+		t.Errorf("invalid payment: %v", saved)
+	}
+}
+
+func TestService_Refund_transfer(t *testing.T) {
+	s := newTestService()
+	from, to, err := s.addTransferAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	outgoing, err := s.Transfer(from.ID, to.ID, 300)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Refund(outgoing.LinkedID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s.balance(from.ID) != 800 || s.balance(to.ID) != 200 {
+		t.Errorf("invalid balances: from - %v, to - %v", s.balance(from.ID), s.balance(to.ID))
+		return
+	}
+
+	saved, err := s.FindPaymetByID(outgoing.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if saved.Status != types.PaymentStatusPartiallyRefunded || saved.Refunded != 100 {
+		t.Errorf("invalid payment: %v", saved)
+	}
+}
@@ -0,0 +1,423 @@
+package wallet
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+// Repository stores accounts, payments and favorites for a Service.
+// Implementations must be safe for concurrent reads and must apply
+// each Batch passed to Save as a whole.
+type Repository interface {
+	LastAccountID() (int64, error)
+	FindAccountByID(id int64) (*types.Account, error)
+	FindAccountsByPhone(phone types.Phone) ([]types.Account, error)
+	Accounts() ([]types.Account, error)
+
+	FindPaymentByID(id string) (*types.Payment, error)
+	FindPaymentsByAccountID(accountID int64) ([]types.Payment, error)
+	Payments() ([]types.Payment, error)
+
+	FindFavoriteByID(id string) (*types.Favorite, error)
+	Favorites() ([]types.Favorite, error)
+
+	Postings() ([]types.Posting, error)
+
+	FindIdempotencyKey(key string) (*types.IdempotencyKey, error)
+	IdempotencyKeys() ([]types.IdempotencyKey, error)
+
+	OutboxEntries(after int64, limit int) ([]OutboxEntry, error)
+	OutboxCursor(relay string) (int64, error)
+	SaveOutboxCursor(relay string, seq int64) error
+
+	Save(batch *Batch) error
+}
+
+// Batch is a set of records which are created or replaced together.
+// Postings are only ever appended. Events describe the changes made by
+// the batch. They are appended to the outbox in the same write and are
+// published by the Service once the batch is saved.
+type Batch struct {
+	Accounts  []*types.Account
+	Payments  []*types.Payment
+	Favorites []*types.Favorite
+	Postings  []*types.Posting
+	Keys      []*types.IdempotencyKey
+	Events    []Event
+}
+
+// DefaultOutboxRetention is the number of outbox entries which are kept
+// for a replay after every relay has delivered them.
+const DefaultOutboxRetention = 1_000
+
+// MemoryRepository keeps records in slices in insertion order and
+// indexes them by ID, by phone and by the account of a payment. Outbox
+// entries passed by the cursors of all relays are trimmed, except for the
+// last retained ones. A relay which has not saved its cursor yet starts
+// from the oldest kept entry.
+type MemoryRepository struct {
+	mu            sync.RWMutex
+	lastAccountID int64
+	accounts      []*types.Account
+	payments      []*types.Payment
+	favorites     []*types.Favorite
+	postings      []types.Posting
+	keys          []*types.IdempotencyKey
+	outbox        []OutboxEntry
+	outboxBase    int64
+	cursors       map[string]int64
+
+	// outboxRetention is the number of passed entries kept for a replay.
+	outboxRetention int
+
+	accountIndex        map[int64]int
+	phoneIndex          map[types.Phone][]int
+	paymentIndex        map[string]int
+	accountPaymentIndex map[int64][]int
+	favoriteIndex       map[string]int
+	keyIndex            map[string]int
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		accountIndex:        make(map[int64]int),
+		phoneIndex:          make(map[types.Phone][]int),
+		paymentIndex:        make(map[string]int),
+		accountPaymentIndex: make(map[int64][]int),
+		favoriteIndex:       make(map[string]int),
+		keyIndex:            make(map[string]int),
+		outboxRetention:     DefaultOutboxRetention,
+	}
+}
+
+func (r *MemoryRepository) LastAccountID() (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lastAccountID, nil
+}
+
+func (r *MemoryRepository) FindAccountByID(id int64) (*types.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.accountIndex[id]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	return copyAccount(r.accounts[i]), nil
+}
+
+func (r *MemoryRepository) FindAccountsByPhone(phone types.Phone) ([]types.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	indexes := r.phoneIndex[phone]
+	accounts := make([]types.Account, len(indexes))
+	for i, index := range indexes {
+		accounts[i] = *r.accounts[index]
+	}
+
+	return accounts, nil
+}
+
+func (r *MemoryRepository) Accounts() ([]types.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	accounts := make([]types.Account, len(r.accounts))
+	for i, account := range r.accounts {
+		accounts[i] = *account
+	}
+
+	return accounts, nil
+}
+
+func (r *MemoryRepository) FindPaymentByID(id string) (*types.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.paymentIndex[id]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+
+	return copyPayment(r.payments[i]), nil
+}
+
+func (r *MemoryRepository) FindPaymentsByAccountID(accountID int64) ([]types.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	indexes := r.accountPaymentIndex[accountID]
+	payments := make([]types.Payment, len(indexes))
+	for i, index := range indexes {
+		payments[i] = *r.payments[index]
+	}
+
+	return payments, nil
+}
+
+func (r *MemoryRepository) Payments() ([]types.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payments := make([]types.Payment, len(r.payments))
+	for i, payment := range r.payments {
+		payments[i] = *payment
+	}
+
+	return payments, nil
+}
+
+func (r *MemoryRepository) FindFavoriteByID(id string) (*types.Favorite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.favoriteIndex[id]
+	if !ok {
+		return nil, ErrFavoriteNotFound
+	}
+
+	return copyFavorite(r.favorites[i]), nil
+}
+
+func (r *MemoryRepository) Favorites() ([]types.Favorite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	favorites := make([]types.Favorite, len(r.favorites))
+	for i, favorite := range r.favorites {
+		favorites[i] = *favorite
+	}
+
+	return favorites, nil
+}
+
+func (r *MemoryRepository) Postings() ([]types.Posting, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	postings := make([]types.Posting, len(r.postings))
+	copy(postings, r.postings)
+
+	return postings, nil
+}
+
+func (r *MemoryRepository) FindIdempotencyKey(key string) (*types.IdempotencyKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.keyIndex[key]
+	if !ok {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+
+	return copyKey(r.keys[i]), nil
+}
+
+func (r *MemoryRepository) IdempotencyKeys() ([]types.IdempotencyKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]types.IdempotencyKey, len(r.keys))
+	for i, key := range r.keys {
+		keys[i] = *key
+	}
+
+	return keys, nil
+}
+
+func (r *MemoryRepository) OutboxEntries(after int64, limit int) ([]OutboxEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// entries up to the base are trimmed, the first kept one has the seq
+	// base+1
+	after -= r.outboxBase
+	if after < 0 {
+		after = 0
+	}
+	if after > int64(len(r.outbox)) {
+		return []OutboxEntry{}, nil
+	}
+
+	entries := r.outbox[after:]
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	result := make([]OutboxEntry, len(entries))
+	copy(result, entries)
+
+	return result, nil
+}
+
+func (r *MemoryRepository) OutboxCursor(relay string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cursors[relay], nil
+}
+
+func (r *MemoryRepository) SaveOutboxCursor(relay string, seq int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cursors == nil {
+		r.cursors = make(map[string]int64)
+	}
+	r.cursors[relay] = seq
+	r.trimOutbox()
+
+	return nil
+}
+
+// trimOutbox drops the entries passed by all cursors but the retained
+// ones. It waits until as many entries as are retained can be dropped, so
+// the kept ones are not copied after every delivery.
+func (r *MemoryRepository) trimOutbox() {
+	if len(r.cursors) == 0 {
+		return
+	}
+
+	passed := int64(-1)
+	for _, seq := range r.cursors {
+		if passed < 0 || seq < passed {
+			passed = seq
+		}
+	}
+
+	n := int(passed-r.outboxBase) - r.outboxRetention
+	if n <= 0 || n < r.outboxRetention {
+		return
+	}
+	if n > len(r.outbox) {
+		n = len(r.outbox)
+	}
+
+	r.outbox = append([]OutboxEntry(nil), r.outbox[n:]...)
+	r.outboxBase += int64(n)
+}
+
+func (r *MemoryRepository) Save(batch *Batch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.accountIndex == nil {
+		r.accountIndex = make(map[int64]int)
+		r.phoneIndex = make(map[types.Phone][]int)
+		r.paymentIndex = make(map[string]int)
+		r.accountPaymentIndex = make(map[int64][]int)
+		r.favoriteIndex = make(map[string]int)
+		r.keyIndex = make(map[string]int)
+	}
+
+	for _, account := range batch.Accounts {
+		r.saveAccount(copyAccount(account))
+	}
+
+	for _, payment := range batch.Payments {
+		r.savePayment(copyPayment(payment))
+	}
+
+	for _, favorite := range batch.Favorites {
+		r.saveFavorite(copyFavorite(favorite))
+	}
+
+	for _, posting := range batch.Postings {
+		r.postings = append(r.postings, *posting)
+	}
+
+	for _, key := range batch.Keys {
+		r.saveKey(copyKey(key))
+	}
+
+	for _, event := range batch.Events {
+		r.outbox = append(r.outbox, OutboxEntry{Seq: r.outboxBase + int64(len(r.outbox)) + 1, Event: copyEvent(event)})
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) saveAccount(account *types.Account) {
+	if account.ID > r.lastAccountID {
+		r.lastAccountID = account.ID
+	}
+
+	i, ok := r.accountIndex[account.ID]
+	if !ok {
+		i = len(r.accounts)
+		r.accounts = append(r.accounts, account)
+		r.accountIndex[account.ID] = i
+		r.phoneIndex[account.Phone] = append(r.phoneIndex[account.Phone], i)
+		return
+	}
+
+	if old := r.accounts[i].Phone; old != account.Phone {
+		r.phoneIndex[old] = removeIndex(r.phoneIndex[old], i)
+		r.phoneIndex[account.Phone] = insertIndex(r.phoneIndex[account.Phone], i)
+	}
+	r.accounts[i] = account
+}
+
+func (r *MemoryRepository) savePayment(payment *types.Payment) {
+	i, ok := r.paymentIndex[payment.ID]
+	if !ok {
+		i = len(r.payments)
+		r.payments = append(r.payments, payment)
+		r.paymentIndex[payment.ID] = i
+		r.accountPaymentIndex[payment.AccountID] = append(r.accountPaymentIndex[payment.AccountID], i)
+		return
+	}
+
+	if old := r.payments[i].AccountID; old != payment.AccountID {
+		r.accountPaymentIndex[old] = removeIndex(r.accountPaymentIndex[old], i)
+		r.accountPaymentIndex[payment.AccountID] = insertIndex(r.accountPaymentIndex[payment.AccountID], i)
+	}
+	r.payments[i] = payment
+}
+
+func (r *MemoryRepository) saveFavorite(favorite *types.Favorite) {
+	i, ok := r.favoriteIndex[favorite.ID]
+	if !ok {
+		r.favoriteIndex[favorite.ID] = len(r.favorites)
+		r.favorites = append(r.favorites, favorite)
+		return
+	}
+
+	r.favorites[i] = favorite
+}
+
+func (r *MemoryRepository) saveKey(key *types.IdempotencyKey) {
+	i, ok := r.keyIndex[key.Key]
+	if !ok {
+		r.keyIndex[key.Key] = len(r.keys)
+		r.keys = append(r.keys, key)
+		return
+	}
+
+	r.keys[i] = key
+}
+
+func removeIndex(indexes []int, index int) []int {
+	for i, value := range indexes {
+		if value == index {
+			return append(indexes[:i], indexes[i+1:]...)
+		}
+	}
+
+	return indexes
+}
+
+func insertIndex(indexes []int, index int) []int {
+	i := sort.SearchInts(indexes, index)
+	indexes = append(indexes, 0)
+	copy(indexes[i+1:], indexes[i:])
+	indexes[i] = index
+
+	return indexes
+}
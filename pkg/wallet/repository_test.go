@@ -0,0 +1,268 @@
+package wallet
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+type countingRepository struct {
+	*MemoryRepository
+	batches []*Batch
+}
+
+func (r *countingRepository) Save(batch *Batch) error {
+	r.batches = append(r.batches, batch)
+	return r.MemoryRepository.Save(batch)
+}
+
+func TestService_NewService_repository(t *testing.T) {
+	repo := &countingRepository{MemoryRepository: NewMemoryRepository()}
+	s := NewService(repo)
+
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Deposit(account.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(account.ID, 10, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(repo.batches) != 3 {
+		t.Errorf("invalid saves count: got %v, want %v", len(repo.batches), 3)
+		return
+	}
+
+	batch := repo.batches[2]
+	if len(batch.Accounts) != 1 || len(batch.Payments) != 1 {
+		t.Errorf("pay must save account and payment together: %v", batch)
+		return
+	}
+
+	saved, err := repo.FindAccountByID(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if saved.Balance != 90 {
+		t.Errorf("invalid balance: got %v, want %v", saved.Balance, 90)
+	}
+}
+
+func TestMemoryRepository_FindAccountByID_copy(t *testing.T) {
+	repo := NewMemoryRepository()
+	err := repo.Save(&Batch{Accounts: []*types.Account{{ID: 1, Phone: "+992000000001", Balance: 10}}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	account, err := repo.FindAccountByID(1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	account.Balance = 100
+
+	saved, err := repo.FindAccountByID(1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if saved.Balance != 10 {
+		t.Errorf("repository must not share records: got %v, want %v", saved.Balance, 10)
+	}
+}
+
+func TestMemoryRepository_FindPaymentsByAccountID(t *testing.T) {
+	repo := NewMemoryRepository()
+	err := repo.Save(&Batch{Payments: []*types.Payment{
+		{ID: "1", AccountID: 1, Amount: 10},
+		{ID: "2", AccountID: 2, Amount: 20},
+		{ID: "3", AccountID: 1, Amount: 30},
+	}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = repo.Save(&Batch{Payments: []*types.Payment{{ID: "1", AccountID: 2, Amount: 10}}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payments, err := repo.FindPaymentsByAccountID(2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(payments) != 2 || payments[0].ID != "1" || payments[1].ID != "2" {
+		t.Errorf("invalid payments: %v", payments)
+		return
+	}
+
+	payments, err = repo.FindPaymentsByAccountID(1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(payments) != 1 || payments[0].ID != "3" {
+		t.Errorf("invalid payments: %v", payments)
+	}
+}
+
+const (
+	benchmarkAccounts = 1_000
+	benchmarkPayments = 100_000
+)
+
+func newBenchmarkRepository(b *testing.B) *MemoryRepository {
+	repo := NewMemoryRepository()
+	batch := &Batch{}
+	for i := 1; i <= benchmarkAccounts; i++ {
+		batch.Accounts = append(batch.Accounts, &types.Account{
+			ID:    int64(i),
+			Phone: types.Phone(fmt.Sprintf("+992%09d", i)),
+		})
+	}
+
+	for i := 0; i < benchmarkPayments; i++ {
+		batch.Payments = append(batch.Payments, &types.Payment{
+			ID:        strconv.Itoa(i),
+			AccountID: int64(i%benchmarkAccounts + 1),
+			Amount:    1,
+		})
+	}
+
+	err := repo.Save(batch)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return repo
+}
+
+func Benchmark_FindPaymentByID_index(b *testing.B) {
+	repo := newBenchmarkRepository(b)
+	id := strconv.Itoa(benchmarkPayments - 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := repo.FindPaymentByID(id)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_FindPaymentByID_scan(b *testing.B) {
+	repo := newBenchmarkRepository(b)
+	id := strconv.Itoa(benchmarkPayments - 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var found *types.Payment
+		for _, payment := range repo.payments {
+			if payment.ID == id {
+				found = payment
+				break
+			}
+		}
+
+		if found == nil {
+			b.Fatal(ErrPaymentNotFound)
+		}
+	}
+}
+
+func Benchmark_FindAccountByPhone_index(b *testing.B) {
+	repo := newBenchmarkRepository(b)
+	phone := types.Phone(fmt.Sprintf("+992%09d", benchmarkAccounts))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accounts, err := repo.FindAccountsByPhone(phone)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(accounts) == 0 {
+			b.Fatal(ErrAccountNotFound)
+		}
+	}
+}
+
+func Benchmark_FindAccountByPhone_scan(b *testing.B) {
+	repo := newBenchmarkRepository(b)
+	phone := types.Phone(fmt.Sprintf("+992%09d", benchmarkAccounts))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var found *types.Account
+		for _, account := range repo.accounts {
+			if account.Phone == phone {
+				found = account
+				break
+			}
+		}
+
+		if found == nil {
+			b.Fatal(ErrAccountNotFound)
+		}
+	}
+}
+
+func Benchmark_FilterPayments_index(b *testing.B) {
+	svc := NewService(newBenchmarkRepository(b))
+	want := benchmarkPayments / benchmarkAccounts
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		payments, err := svc.FilterPayments(1, 4)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(payments) != want {
+			b.Fatalf("invalid result, got %v, want %v", len(payments), want)
+		}
+	}
+}
+
+func Benchmark_FilterPayments_scan(b *testing.B) {
+	svc := NewService(newBenchmarkRepository(b))
+	want := benchmarkPayments / benchmarkAccounts
+	filter := func(payment types.Payment) bool {
+		return payment.AccountID == 1
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		payments, err := svc.FilterPaymentsByFn(filter, 4)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(payments) != want {
+			b.Fatalf("invalid result, got %v, want %v", len(payments), want)
+		}
+	}
+}
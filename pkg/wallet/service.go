@@ -2,13 +2,15 @@ package wallet
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/AlisherGulomzoda/wallet/pkg/types"
 	"github.com/google/uuid"
@@ -21,172 +23,363 @@ var (
 	ErrBalanceNotAmount      = errors.New("balance little then amount")
 	ErrPaymentNotFound       = errors.New("payment not found")
 	ErrFavoriteNotFound      = errors.New("favorit not found")
-	ErrFileNotFound			 = errors.New("file not found")
+	ErrFileNotFound          = errors.New("file not found")
+	ErrTransferToSameAccount = errors.New("transfer to the same account")
 )
 
 type Service struct {
-	nextAccountID int64
-	accounts      []*types.Account
-	payments      []*types.Payment
-	favorites     []*types.Favorite
+	mu         sync.RWMutex
+	once       sync.Once
+	repository Repository
+	clock      Clock
+	currency   types.Currency
+	rates      RateProvider
+	rounding   RoundingMode
+	notifier   *Notifier
+	lowBalance types.Money
+	events     *EventBus
+}
+
+func NewService(repository Repository, options ...Option) *Service {
+	s := &Service{repository: repository}
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+func (s *Service) repo() Repository {
+	s.once.Do(func() {
+		if s.repository == nil {
+			s.repository = NewMemoryRepository()
+		}
+	})
+
+	return s.repository
+}
+
+// save stamps created and updated time of the records, saves them and
+// publishes the events of the batch.
+func (s *Service) save(batch *Batch) error {
+	now := s.now()
+	for _, account := range batch.Accounts {
+		if account.CreatedAt.IsZero() {
+			account.CreatedAt = now
+		}
+		account.UpdatedAt = now
+	}
+
+	for _, payment := range batch.Payments {
+		if payment.CreatedAt.IsZero() {
+			payment.CreatedAt = now
+		}
+		payment.UpdatedAt = now
+	}
+
+	for _, favorite := range batch.Favorites {
+		if favorite.CreatedAt.IsZero() {
+			favorite.CreatedAt = now
+		}
+		favorite.UpdatedAt = now
+	}
+
+	for _, key := range batch.Keys {
+		if key.CreatedAt.IsZero() {
+			key.CreatedAt = now
+		}
+	}
+	stampEvents(batch.Events, now)
+
+	err := s.repo().Save(batch)
+	if err != nil {
+		return err
+	}
+
+	if s.events != nil {
+		s.events.Publish(batch.Events...)
+	}
+
+	return nil
 }
 
 func (s *Service) RegisterAccount(phone types.Phone) (*types.Account, error) {
-	for _, account := range s.accounts {
-		if account.Phone == phone {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.openAccount(phone, s.defaultCurrency())
+}
+
+// OpenAccount opens an account in the currency. A phone may have one
+// account in each currency.
+func (s *Service) OpenAccount(phone types.Phone, currency types.Currency) (*types.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.openAccount(phone, currency)
+}
+
+func (s *Service) openAccount(phone types.Phone, currency types.Currency) (*types.Account, error) {
+	phone, err := NormalizePhone(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.repo().FindAccountsByPhone(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, account := range accounts {
+		if account.Currency == currency {
 			return nil, ErrPhoneAlreadyRegitered
 		}
 	}
 
-	s.nextAccountID++
+	lastAccountID, err := s.repo().LastAccountID()
+	if err != nil {
+		return nil, err
+	}
+
 	account := &types.Account{
-		ID:      s.nextAccountID,
-		Phone:   phone,
-		Balance: 0,
+		ID:       lastAccountID + 1,
+		Phone:    phone,
+		Balance:  0,
+		Currency: currency,
+		Status:   types.AccountStatusActive,
 	}
-	s.accounts = append(s.accounts, account)
 
-	return account, nil
+	err = s.save(&Batch{
+		Accounts: []*types.Account{account},
+		Events: []Event{&AccountRegistered{
+			EventHeader: EventHeader{AccountID: account.ID},
+			Phone:       account.Phone,
+			Currency:    account.Currency,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	return account, nil
 }
 
-func (s *Service) Deposit(accountID int64, amount types.Money) error {
+func (s *Service) Deposit(accountID int64, amount types.Money, options ...PayOption) error {
 	if amount <= 0 {
 		return ErrAmountMustGreateZero
 	}
 
-	var account *types.Account
-	for _, acc := range s.accounts {
-		if acc.ID == accountID {
-			account = acc
-			break
-		}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := newPayOptions(options)
+	request := fingerprint("deposit", accountID, amount, o.currency)
+	done, err := s.replay(o.key, request)
+	if err != nil || done != nil {
+		return err
+	}
+
+	account, err := s.repo().FindAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	err = checkActive(account, "deposit")
+	if err != nil {
+		return err
+	}
+
+	cash, _, err := s.convert(cashOf(account, amount, o), account.Currency)
+	if err != nil {
+		return err
+	}
+
+	// a small amount in another currency may round to nothing
+	if cash.Amount <= 0 {
+		return ErrAmountMustGreateZero
+	}
+
+	err = credit(account, cash)
+	if err != nil {
+		return err
 	}
 
-	if account == nil {
-		return ErrAccountNotFound
+	err = s.save(&Batch{
+		Accounts: []*types.Account{account},
+		Postings: []*types.Posting{s.newPosting(ExternalAccountID, account.ID, cash, "")},
+		Keys:     keysOf(o.key, request, ""),
+		Events: []Event{&Deposited{
+			EventHeader: EventHeader{AccountID: account.ID},
+			Amount:      cash.Amount,
+			Currency:    cash.Currency,
+			Balance:     account.Balance,
+		}},
+	})
+	if err != nil {
+		return err
 	}
 
-	account.Balance += amount
+	s.notify("Account %d: deposit %d %s, balance %d %s", account.ID, cash.Amount, cash.Currency, account.Balance, account.Currency)
 
 	return nil
+}
+
+func (s *Service) Pay(accountID int64, amount types.Money, category types.PaymentCategoty, options ...PayOption) (*types.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	return s.pay(accountID, amount, category, newPayOptions(options))
 }
 
-func (s *Service) Pay(accountID int64, amount types.Money, category types.PaymentCategoty) (*types.Payment, error) {
-	if amount <= 0 {
-		return nil, ErrAmountMustGreateZero
+func (s *Service) pay(accountID int64, amount types.Money, category types.PaymentCategoty, o *payOptions) (*types.Payment, error) {
+	request := fingerprint("pay", accountID, amount, category, o.currency)
+	done, err := s.replay(o.key, request)
+	if err != nil {
+		return nil, err
+	}
+	if done != nil {
+		return s.repo().FindPaymentByID(done.PaymentID)
 	}
 
-	var account *types.Account
-	for _, acc := range s.accounts {
-		if acc.ID == accountID {
-			account = acc
-			break
-		}
+	account, err := s.repo().FindAccountByID(accountID)
+	if err != nil {
+		return nil, err
 	}
 
-	if account == nil {
-		return nil, ErrAccountNotFound
+	err = checkActive(account, "pay")
+	if err != nil {
+		return nil, err
+	}
+
+	requested := cashOf(account, amount, o)
+	cash, rate, err := s.convert(requested, account.Currency)
+	if err != nil {
+		return nil, err
 	}
 
-	if account.Balance < amount {
-		return nil, ErrBalanceNotAmount
+	err = checkPayment(account, cash)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkLimits(account, category, cash)
+	if err != nil {
+		return nil, err
+	}
+
+	before := account.Balance
+	err = debit(account, cash)
+	if err != nil {
+		return nil, err
 	}
 
-	account.Balance -= amount
 	paymentID := uuid.New().String()
 	payment := &types.Payment{
 		ID:        paymentID,
 		AccountID: account.ID,
-		Amount:    amount,
+		Amount:    cash.Amount,
 		Category:  category,
 		Status:    types.PaymentStatusInProgress,
+		Type:      types.PaymentTypePayment,
+		Currency:  cash.Currency,
+	}
+	setOriginal(payment, requested, rate)
+
+	err = s.save(&Batch{
+		Accounts: []*types.Account{account},
+		Payments: []*types.Payment{payment},
+		Postings: []*types.Posting{s.newPosting(account.ID, ExternalAccountID, cash, payment.ID)},
+		Keys:     keysOf(o.key, request, payment.ID),
+		Events:   []Event{paymentCreated(payment, account)},
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	s.payments = append(s.payments, payment)
+	s.notify("Account %d: payment %d %s for %s, balance %d %s", account.ID, cash.Amount, cash.Currency, category, account.Balance, account.Currency)
+	s.notifyLowBalance(account, before)
 
 	return payment, nil
-
 }
 
-func (s *Service) FindAccountByID(accountID int64) (*types.Account, error) {
-	var account *types.Account
-	for _, acc := range s.accounts {
-		if acc.ID == accountID {
-			account = acc
-			break
-		}
+func checkPayment(account *types.Account, cash types.Cash) error {
+	if cash.Amount <= 0 {
+		return ErrAmountMustGreateZero
 	}
 
-	if account == nil {
-		return nil, ErrAccountNotFound
+	balance, err := balanceOf(account).Sub(cash)
+	if err != nil {
+		return err
 	}
 
-	return account, nil
+	if balance.Amount < -account.Overdraft {
+		return ErrBalanceNotAmount
+	}
+
+	return nil
+}
+
+func (s *Service) FindAccountByID(accountID int64) (*types.Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.repo().FindAccountByID(accountID)
 }
 
+// Reject fails the payment and returns its amount to the account. A
+// payment can't be rejected once a refund has been made for it, the rest
+// is returned with Refund then and ErrPaymentRefunded is returned.
 func (s *Service) Reject(paymentID string) error {
-	payment, err := s.FindPaymetByID(paymentID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, err := s.findRefundable(paymentID)
 	if err != nil {
 		return err
 	}
 
-	var account *types.Account
-	for _, acc := range s.accounts {
-		if acc.ID == payment.AccountID {
-			account = acc
-		}
+	if payment.Status == types.PaymentStatusFail {
+		return nil
 	}
 
-	if account == nil {
-		return ErrAccountNotFound
+	if payment.Refunded != 0 {
+		return ErrPaymentRefunded
 	}
 
-	payment.Status = types.PaymentStatusFail
-	account.Balance += payment.Amount
-	payment.Amount = 0
-
-	return nil
-
+	return s.refund(payment, payment.Amount-payment.Refunded, types.PaymentStatusFail, "reject")
 }
 
 func (s *Service) FindPaymetByID(paymentID string) (*types.Payment, error) {
-	for _, pay := range s.payments {
-		if pay.ID == paymentID {
-			return pay, nil
-		}
-	}
-
-	return nil, ErrPaymentNotFound
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
+	return s.repo().FindPaymentByID(paymentID)
 }
 
 func (s *Service) Repeat(paymentID string) (*types.Payment, error) {
-	var payment *types.Payment
-	for _, pay := range s.payments {
-		if pay.ID == paymentID {
-			payment = pay
-			break
-		}
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	payment, err := s.Pay(payment.AccountID, payment.Amount, payment.Category)
+	payment, err := s.repo().FindPaymentByID(paymentID)
 	if err != nil {
-		return nil, ErrPaymentNotFound
+		return nil, err
 	}
 
-	return payment, nil
+	if isTransfer(payment) {
+		return s.repeatTransfer(payment)
+	}
 
+	original := originalOf(payment)
+	return s.pay(payment.AccountID, original.Amount, payment.Category, &payOptions{currency: original.Currency})
 }
 
 func (s *Service) findPaymentAndAccountByPaymentID(paymentID string) (*types.Payment, *types.Account, error) {
-	payment, err := s.FindPaymetByID(paymentID)
+	payment, err := s.repo().FindPaymentByID(paymentID)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	account, err := s.FindAccountByID(payment.AccountID)
+	account, err := s.repo().FindAccountByID(payment.AccountID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -195,47 +388,61 @@ func (s *Service) findPaymentAndAccountByPaymentID(paymentID string) (*types.Pay
 }
 
 func (s *Service) FavoritePayment(paymentID string, name string) (*types.Favorite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	targetPayment, targetAccount, err := s.findPaymentAndAccountByPaymentID(paymentID)
 	if err != nil {
 		return nil, err
 	}
 
+	original := originalOf(targetPayment)
 	favorite := &types.Favorite{
 		ID:        uuid.New().String(),
 		AccountID: targetAccount.ID,
 		Name:      name,
-		Amount:    targetPayment.Amount,
+		Amount:    original.Amount,
 		Category:  targetPayment.Category,
+		Currency:  original.Currency,
 	}
 
-	s.favorites = append(s.favorites, favorite)
+	err = s.save(&Batch{
+		Favorites: []*types.Favorite{favorite},
+		Events: []Event{&FavoriteCreated{
+			EventHeader: EventHeader{AccountID: favorite.AccountID},
+			FavoriteID:  favorite.ID,
+			PaymentID:   targetPayment.ID,
+			Name:        favorite.Name,
+			Amount:      favorite.Amount,
+			Category:    favorite.Category,
+			Currency:    favorite.Currency,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	return favorite, nil
 }
 
-func (s *Service) PayFromFavorite(favoriteID string) (*types.Payment, error) {
-	var favorite *types.Favorite
-	for _, fav := range s.favorites {
-		if fav.ID == favoriteID {
-			favorite = fav
-			break
-		}
-	}
-
-	if favorite == nil {
-		return nil, ErrFavoriteNotFound
-	}
+func (s *Service) PayFromFavorite(favoriteID string, options ...PayOption) (*types.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	var payment *types.Payment
-	payment, err := s.Pay(favorite.AccountID, favorite.Amount, favorite.Category)
+	favorite, err := s.repo().FindFavoriteByID(favoriteID)
 	if err != nil {
 		return nil, err
 	}
 
-	return payment, nil
+	o := newPayOptions(options)
+	o.currency = favorite.Currency
+	return s.pay(favorite.AccountID, favorite.Amount, favorite.Category, o)
 }
 
 func (s *Service) ExportToFile(path string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	wd, err := os.Getwd()
 	if err != nil {
 		log.Print(err)
@@ -245,326 +452,735 @@ func (s *Service) ExportToFile(path string) error {
 
 	file, err := os.Open(path)
 	if err != nil {
-		return err
+		return err
+	}
+
+	defer func() {
+		if cerr := file.Close(); cerr != nil {
+			log.Print(cerr)
+		}
+	}()
+
+	accounts, err := s.repo().Accounts()
+	if err != nil {
+		return err
+	}
+
+	result := ""
+	for _, account := range accounts {
+		result += formatRecord('|',
+			strconv.FormatInt(int64(account.ID), 10),
+			string(account.Phone),
+			strconv.FormatInt(int64(account.Balance), 10),
+		)
+	}
+
+	err = ioutil.WriteFile(path, []byte(result), 0666)
+	if err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+func (s *Service) ImportFromFile(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil {
+			log.Print(cerr)
+		}
+	}()
+
+	content := make([]byte, 0)
+	buf := make([]byte, 4096)
+
+	for {
+		read, err := file.Read(buf)
+		if err == io.EOF {
+			content = append(content, buf[:read]...)
+			break
+		}
+
+		if err != nil {
+			log.Print(err)
+			return err
+		}
+
+		content = append(content, buf[:read]...)
+	}
+
+	r := newDumpReader(filepath.Base(path), string(content), '|', []int{3})
+	batch := &Batch{}
+	for {
+		ok, err := r.next()
+		if err != nil {
+			log.Println(err)
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		id, err := r.int(0, "id")
+		if err != nil {
+			log.Println(err)
+			return err
+		}
+
+		balance, err := r.money(2, "balance")
+		if err != nil {
+			log.Println(err)
+			return err
+		}
+
+		phone, err := NormalizePhone(types.Phone(r.field(1)))
+		if err != nil {
+			log.Println(err)
+			return r.err("phone", err)
+		}
+
+		newAccount := &types.Account{
+			ID:       int64(id),
+			Currency: s.defaultCurrency(),
+			Status:   types.AccountStatusActive,
+		}
+
+		var current types.Money
+		account, err := s.repo().FindAccountByID(newAccount.ID)
+		if err == nil {
+			current = account.Balance
+			newAccount = account
+		}
+		newAccount.Phone = phone
+		newAccount.Balance = balance
+
+		batch.Accounts = append(batch.Accounts, newAccount)
+		if current != newAccount.Balance {
+			batch.Postings = append(batch.Postings, s.adjustmentPosting(newAccount, current, newAccount.Balance))
+		}
+	}
+
+	return s.repo().Save(batch)
+}
+
+// Export writes every collection to its dump in the dir, empty ones
+// included, and then the manifest which describes them. The manifest is
+// written last, so dumps left by a crash in the middle of Export don't
+// match it and are refused by Import.
+func (s *Service) Export(dir string) error {
+	s.mu.RLock()
+	files, err := s.dumps()
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	manifest := &Manifest{Version: ManifestVersion, CreatedAt: s.now().UTC()}
+	for _, file := range files {
+		err := actionByFile(filepath.Join(dir, file.name), file.data)
+		if err != nil {
+			return err
+		}
+
+		manifest.Files = append(manifest.Files, file.entry())
+	}
+
+	return writeManifest(dir, manifest)
+}
+
+type dump struct {
+	name    string
+	data    string
+	records int
+}
+
+// dumps formats every collection in the order Import applies them.
+func (s *Service) dumps() ([]dump, error) {
+	accounts, err := s.repo().Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	accountsDump := dump{name: accountsFile, records: len(accounts)}
+	for _, account := range accounts {
+		accountsDump.data += formatLine(accountRecord(account)...)
+	}
+
+	payments, err := s.repo().Payments()
+	if err != nil {
+		return nil, err
+	}
+
+	paymentsDump := dump{name: paymentsFile, records: len(payments)}
+	for _, payment := range payments {
+		paymentsDump.data += formatLine(paymentRecord(payment)...)
+	}
+
+	favorites, err := s.repo().Favorites()
+	if err != nil {
+		return nil, err
+	}
+
+	favoritesDump := dump{name: favoritesFile, records: len(favorites)}
+	for _, favorite := range favorites {
+		favoritesDump.data += formatLine(favoriteRecord(favorite)...)
+	}
+
+	keys, err := s.repo().IdempotencyKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	keysDump := dump{name: keysFile, records: len(keys)}
+	for _, key := range keys {
+		keysDump.data += formatLine(key.Key, key.Fingerprint, key.PaymentID, formatTime(key.CreatedAt))
+	}
+
+	return []dump{accountsDump, paymentsDump, favoritesDump, keysDump}, nil
+}
+
+func accountRecord(account types.Account) []string {
+	fields := []string{
+		strconv.Itoa(int(account.ID)),
+		string(account.Phone),
+		strconv.Itoa(int(account.Balance)),
+		formatTime(account.CreatedAt),
+		formatTime(account.UpdatedAt),
+		string(account.Currency),
+	}
+	fields = append(fields, formatLimits(account.Limits)...)
+
+	return append(fields, strconv.Itoa(int(account.Overdraft)), string(account.Status))
+}
+
+func paymentRecord(payment types.Payment) []string {
+	return []string{
+		payment.ID,
+		strconv.Itoa(int(payment.AccountID)),
+		strconv.Itoa(int(payment.Amount)),
+		string(payment.Category),
+		string(payment.Status),
+		string(payment.Type),
+		payment.LinkedID,
+		strconv.Itoa(int(payment.Refunded)),
+		formatTime(payment.CreatedAt),
+		formatTime(payment.UpdatedAt),
+		string(payment.Currency),
+		strconv.Itoa(int(payment.OriginalAmount)),
+		string(payment.OriginalCurrency),
+		strconv.Itoa(int(payment.Rate)),
+	}
+}
+
+func favoriteRecord(favorite types.Favorite) []string {
+	return []string{
+		favorite.ID,
+		strconv.Itoa(int(favorite.AccountID)),
+		favorite.Name,
+		strconv.Itoa(int(favorite.Amount)),
+		string(favorite.Category),
+		formatTime(favorite.CreatedAt),
+		formatTime(favorite.UpdatedAt),
+		string(favorite.Currency),
+	}
+}
+
+// Import loads the dumps written by Export from the dir. The dumps are
+// checked against the manifest and are applied together, or not at all
+// when any of them is invalid. Dumps written before the manifest was
+// introduced are loaded without the checks, a missing dump is skipped.
+func (s *Service) Import(dir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := readDumps(dir)
+	if err != nil {
+		return err
+	}
+
+	batch := &Batch{}
+	for _, file := range files {
+		err = s.parseDump(file.name, file.data, batch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.saveImported(batch)
+}
+
+func (s *Service) parseDump(name string, data string, batch *Batch) error {
+	switch name {
+	case accountsFile:
+		return s.parseAccounts(name, data, batch)
+	case paymentsFile:
+		return s.parsePayments(name, data, batch)
+	case favoritesFile:
+		return s.parseFavorites(name, data, batch)
+	case keysFile:
+		return s.parseKeys(name, data, batch)
+	}
+
+	return fmt.Errorf("unknown dump %s", name)
+}
+
+// importFile applies a single dump. A missing dump is skipped.
+func (s *Service) importFile(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byteData, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Println(ErrFileNotFound.Error())
+		return nil
+	}
+
+	batch := &Batch{}
+	err = s.parseDump(filepath.Base(path), string(byteData), batch)
+	if err != nil {
+		return err
+	}
+
+	return s.saveImported(batch)
+}
+
+// saveImported saves the batch keeping the imported times of the records.
+func (s *Service) saveImported(batch *Batch) error {
+	stampEvents(batch.Events, s.now())
+
+	err := s.repo().Save(batch)
+	if err != nil {
+		return err
+	}
+
+	if s.events != nil {
+		s.events.Publish(batch.Events...)
+	}
+
+	return nil
+}
+
+func (s *Service) actionByAccounts(path string) error {
+	return s.importFile(path)
+}
+
+func (s *Service) parseAccounts(file string, datas string, batch *Batch) error {
+	r := newDumpReader(file, datas, recordSeparator, accountFields)
+	for {
+		ok, err := r.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		err = s.parseAccount(r, batch)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Service) parseAccount(r *dumpReader, batch *Batch) error {
+	id, err := r.int(0, "id")
+	if err != nil {
+		return err
+	}
+
+	phone, err := NormalizePhone(types.Phone(r.field(1)))
+	if err != nil {
+		return r.err("phone", err)
+	}
+
+	balance, err := r.money(2, "balance")
+	if err != nil {
+		return err
+	}
+
+	var createdAt, updatedAt time.Time
+	if r.has(4) {
+		createdAt, err = r.time(3, "created_at")
+		if err != nil {
+			return err
+		}
+
+		updatedAt, err = r.time(4, "updated_at")
+		if err != nil {
+			return err
+		}
+	}
+
+	currency := s.defaultCurrency()
+	if r.has(5) {
+		currency = types.Currency(r.field(5))
+	}
+
+	var limits types.Limits
+	if r.has(9) {
+		limits, err = parseLimits(r.fields[6:10])
+		if err != nil {
+			return r.err("limits", err)
+		}
+	}
+
+	var overdraft types.Money
+	if r.has(10) {
+		overdraft, err = r.money(10, "overdraft")
+		if err != nil {
+			return err
+		}
+	}
+
+	status := types.AccountStatusActive
+	if r.has(11) {
+		status = types.AccountStatus(r.field(11))
+	}
+
+	account, err := s.repo().FindAccountByID(int64(id))
+	if err != nil {
+		acc, err := s.importedAccount(int64(id), phone, currency, batch)
+		if errors.Is(err, ErrPhoneAlreadyRegitered) {
+			return r.err("phone", err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if balance != 0 {
+			batch.Postings = append(batch.Postings, s.adjustmentPosting(acc, 0, balance))
+		}
+		acc.Balance = balance
+		acc.Limits = limits
+		acc.Overdraft = overdraft
+		acc.Status = status
+		acc.CreatedAt = createdAt
+		acc.UpdatedAt = updatedAt
+		batch.Accounts = append(batch.Accounts, acc)
+	} else {
+		account.Currency = currency
+		if account.Balance != balance {
+			batch.Postings = append(batch.Postings, s.adjustmentPosting(account, account.Balance, balance))
+		}
+		account.Phone = phone
+		account.Balance = balance
+		account.Limits = limits
+		account.Overdraft = overdraft
+		account.Status = status
+		account.CreatedAt = createdAt
+		account.UpdatedAt = updatedAt
+		batch.Accounts = append(batch.Accounts, account)
+	}
+
+	return nil
+}
+
+// importedAccount registers an account of a dump with its ID in the
+// batch, so the payments of the dump keep pointing to it.
+func (s *Service) importedAccount(id int64, phone types.Phone, currency types.Currency, batch *Batch) (*types.Account, error) {
+	accounts, err := s.repo().FindAccountsByPhone(phone)
+	if err != nil {
+		return nil, err
 	}
 
-	defer func() {
-		if cerr := file.Close(); cerr != nil {
-			log.Print(cerr)
+	for _, account := range append(accounts, accountsOf(batch)...) {
+		if account.Phone == phone && account.Currency == currency {
+			return nil, ErrPhoneAlreadyRegitered
 		}
-	}()
-
-	result := ""
-	for _, account := range s.accounts {
-		result += strconv.FormatInt(int64(account.ID), 10) + ";"
-		result += string(account.Phone) + ";"
-		result += strconv.FormatInt(int64(account.Balance), 10) + "|"
 	}
 
-	err = ioutil.WriteFile(path, []byte(result), 0666)
-	if err != nil {
-		return err
+	account := &types.Account{
+		ID:       id,
+		Phone:    phone,
+		Currency: currency,
+		Status:   types.AccountStatusActive,
 	}
+	batch.Events = append(batch.Events, &AccountRegistered{
+		EventHeader: EventHeader{AccountID: account.ID},
+		Phone:       account.Phone,
+		Currency:    account.Currency,
+	})
 
-	return nil
-
+	return account, nil
 }
 
-func (s *Service) ImportFromFile(path string) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
+func accountsOf(batch *Batch) []types.Account {
+	accounts := make([]types.Account, len(batch.Accounts))
+	for i, account := range batch.Accounts {
+		accounts[i] = *account
 	}
-	defer func() {
-		if cerr := file.Close(); cerr != nil {
-			log.Print(cerr)
-		}
-	}()
 
-	content := make([]byte, 0)
-	buf := make([]byte, 4096)
+	return accounts
+}
 
+func (s *Service) actionByPayments(path string) error {
+	return s.importFile(path)
+}
+
+func (s *Service) parsePayments(file string, datas string, batch *Batch) error {
+	r := newDumpReader(file, datas, recordSeparator, paymentFields)
 	for {
-		read, err := file.Read(buf)
-		if err == io.EOF {
-			content = append(content, buf[:read]...)
-			break
+		ok, err := r.next()
+		if err != nil || !ok {
+			return err
 		}
 
+		err = s.parsePayment(r, batch)
 		if err != nil {
-			log.Print(err)
 			return err
 		}
-
-		content = append(content, buf[:read]...)
 	}
+}
 
-	data := string(content)
-	splitSlice := strings.Split(data, "|")
-
-	for _, split := range splitSlice {
-		if split != "" {
-			datas := strings.Split(split, ";")
-
-			id, err := strconv.Atoi(datas[0])
-			if err != nil {
-				log.Println(err)
-				return err
-			}
-
-			balance, err := strconv.Atoi(datas[2])
-			if err != nil {
-				log.Println(err)
-				return err
-			}
+func (s *Service) parsePayment(r *dumpReader, batch *Batch) error {
+	id := r.field(0)
 
-			newAccount := &types.Account{
-				ID:      int64(id),
-				Phone:   types.Phone(datas[1]),
-				Balance: types.Money(balance),
-			}
+	accountID, err := r.int(1, "account_id")
+	if err != nil {
+		return err
+	}
 
-			s.accounts = append(s.accounts, newAccount)
-		}
+	amount, err := r.money(2, "amount")
+	if err != nil {
+		return err
 	}
 
-	return nil
+	category := types.PaymentCategoty(r.field(3))
 
-}
+	status := types.PaymentStatus(r.field(4))
 
-func (s *Service) Export(dir string) error {
-	if s.accounts != nil {
-		result := ""
-		for _, account := range s.accounts {
-			result += strconv.Itoa(int(account.ID)) + ";"
-			result += string(account.Phone) + ";"
-			result += strconv.Itoa(int(account.Balance)) + "\n"
-		}
+	paymentType := types.PaymentTypePayment
+	linkedID := ""
+	if r.has(6) {
+		paymentType = types.PaymentType(r.field(5))
+		linkedID = r.field(6)
+	}
 
-		err := actionByFile(dir+"/accounts.dump", result)
+	var refunded types.Money
+	if r.has(7) {
+		refunded, err = r.money(7, "refunded")
 		if err != nil {
 			return err
 		}
 	}
 
-	if s.payments != nil {
-		result := ""
-		for _, payment := range s.payments {
-			result += payment.ID + ";"
-			result += strconv.Itoa(int(payment.AccountID)) + ";"
-			result += strconv.Itoa(int(payment.Amount)) + ";"
-			result += string(payment.Category) + ";"
-			result += string(payment.Status) + "\n"
+	var createdAt, updatedAt time.Time
+	if r.has(9) {
+		createdAt, err = r.time(8, "created_at")
+		if err != nil {
+			return err
 		}
 
-		err := actionByFile(dir+"/payments.dump", result)
+		updatedAt, err = r.time(9, "updated_at")
 		if err != nil {
 			return err
 		}
 	}
 
-	if s.favorites != nil {
-		result := ""
-		for _, favorite := range s.favorites {
-			result += favorite.ID + ";"
-			result += strconv.Itoa(int(favorite.AccountID)) + ";"
-			result += favorite.Name + ";"
-			result += strconv.Itoa(int(favorite.Amount)) + ";"
-			result += string(favorite.Category) + "\n"
-		}
+	currency := s.defaultCurrency()
+	if r.has(10) {
+		currency = types.Currency(r.field(10))
+	}
 
-		err := actionByFile(dir+"/favorites.dump", result)
+	var originalAmount types.Money
+	originalCurrency, rate := types.Currency(""), 0
+	if r.has(13) {
+		originalAmount, err = r.money(11, "original_amount")
 		if err != nil {
 			return err
 		}
-	}
-
-	return nil
-}
 
-func (s *Service) actionByAccounts(path string) error {
-	byteData, err := ioutil.ReadFile(path)
-	if err == nil {
-		datas := string(byteData)
-		splits := strings.Split(datas, "\n")
+		originalCurrency = types.Currency(r.field(12))
 
-		for _, split := range splits {
-			if len(split) == 0 {
-				break
-			}
+		rate, err = r.int(13, "rate")
+		if err != nil {
+			return err
+		}
+	}
 
-			data := strings.Split(split, ";")
+	payment, err := s.repo().FindPaymentByID(id)
+	if err != nil {
+		newPayment := &types.Payment{
+			ID:        id,
+			AccountID: int64(accountID),
+			Amount:    amount,
+			Category:  category,
+			Status:    status,
+			Type:      paymentType,
+			LinkedID:  linkedID,
+			Refunded:  refunded,
+			Currency:  currency,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+
+			OriginalAmount:   originalAmount,
+			OriginalCurrency: originalCurrency,
+			Rate:             types.Rate(rate),
+		}
 
-			id, err := strconv.Atoi(data[0])
-			if err != nil {
-				log.Println("can't parse str to int")
-				return err
-			}
+		batch.Payments = append(batch.Payments, newPayment)
+	} else {
+		payment.AccountID = int64(accountID)
+		payment.Amount = amount
+		payment.Category = category
+		payment.Status = status
+		payment.Type = paymentType
+		payment.LinkedID = linkedID
+		payment.Refunded = refunded
+		payment.Currency = currency
+		payment.CreatedAt = createdAt
+		payment.UpdatedAt = updatedAt
+		payment.OriginalAmount = originalAmount
+		payment.OriginalCurrency = originalCurrency
+		payment.Rate = types.Rate(rate)
+		batch.Payments = append(batch.Payments, payment)
+	}
 
-			phone := types.Phone(data[1])
+	return nil
+}
 
-			balance, err := strconv.Atoi(data[2])
-			if err != nil {
-				log.Println("can't parse str to int")
-				return err
-			}
+func (s *Service) actionByFavorites(path string) error {
+	return s.importFile(path)
+}
 
-			account, err := s.FindAccountByID(int64(id))
-			if err != nil {
-				acc, err := s.RegisterAccount(phone)
-				if err != nil {
-					log.Println("err from register account")
-					return err
-				}
+func (s *Service) parseFavorites(file string, datas string, batch *Batch) error {
+	r := newDumpReader(file, datas, recordSeparator, favoriteFields)
+	for {
+		ok, err := r.next()
+		if err != nil || !ok {
+			return err
+		}
 
-				acc.Balance = types.Money(balance)
-			} else {
-				account.Phone = phone
-				account.Balance = types.Money(balance)
-			}
+		err = s.parseFavorite(r, batch)
+		if err != nil {
+			return err
 		}
-	} else {
-		log.Println(ErrFileNotFound.Error())
 	}
-
-	return nil
 }
 
-func (s *Service) actionByPayments(path string) error {
-	byteData, err := ioutil.ReadFile(path)
-	if err == nil {
-		datas := string(byteData)
-		splits := strings.Split(datas, "\n")
+func (s *Service) parseFavorite(r *dumpReader, batch *Batch) error {
+	id := r.field(0)
 
-		for _, split := range splits {
-			if len(split) == 0 {
-				break
-			}
+	accountID, err := r.int(1, "account_id")
+	if err != nil {
+		return err
+	}
 
-			data := strings.Split(split, ";")
-			id := data[0]
+	name := r.field(2)
 
-			accountID, err := strconv.Atoi(data[1])
-			if err != nil {
-				log.Println("can't parse str to int")
-				return err
-			}
+	amount, err := r.money(3, "amount")
+	if err != nil {
+		return err
+	}
 
-			amount, err := strconv.Atoi(data[2])
-			if err != nil {
-				log.Println("can't parse str to int")
-				return err
-			}
+	category := types.PaymentCategoty(r.field(4))
 
-			category := types.PaymentCategoty(data[3])
+	var createdAt, updatedAt time.Time
+	if r.has(6) {
+		createdAt, err = r.time(5, "created_at")
+		if err != nil {
+			return err
+		}
 
-			status := types.PaymentStatus(data[4])
+		updatedAt, err = r.time(6, "updated_at")
+		if err != nil {
+			return err
+		}
+	}
 
-			payment, err := s.FindPaymetByID(id)
-			if err != nil {
-				newPayment := &types.Payment{
-					ID:        id,
-					AccountID: int64(accountID),
-					Amount:    types.Money(amount),
-					Category:  types.PaymentCategoty(category),
-					Status:    types.PaymentStatus(status),
-				}
+	currency := s.defaultCurrency()
+	if r.has(7) {
+		currency = types.Currency(r.field(7))
+	}
 
-				s.payments = append(s.payments, newPayment)
-			} else {
-				payment.AccountID = int64(accountID)
-				payment.Amount = types.Money(amount)
-				payment.Category = category
-				payment.Status = status
-			}
+	favorite, err := s.repo().FindFavoriteByID(id)
+	if err != nil {
+		newFavorite := &types.Favorite{
+			ID:        id,
+			AccountID: int64(accountID),
+			Name:      name,
+			Amount:    amount,
+			Category:  category,
+			Currency:  currency,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
 		}
+
+		batch.Favorites = append(batch.Favorites, newFavorite)
 	} else {
-		log.Println(ErrFileNotFound.Error())
+		favorite.AccountID = int64(accountID)
+		favorite.Name = name
+		favorite.Amount = amount
+		favorite.Category = category
+		favorite.Currency = currency
+		favorite.CreatedAt = createdAt
+		favorite.UpdatedAt = updatedAt
+		batch.Favorites = append(batch.Favorites, favorite)
 	}
 
 	return nil
 }
 
-func (s *Service) actionByFavorites(path string) error {
-	byteData, err := ioutil.ReadFile(path)
-	if err == nil {
-		datas := string(byteData)
-		splits := strings.Split(datas, "\n")
-
-		for _, split := range splits {
-			if len(split) == 0 {
-				break
-			}
+func (s *Service) parseKeys(file string, datas string, batch *Batch) error {
+	r := newDumpReader(file, datas, recordSeparator, keyFields)
+	for {
+		ok, err := r.next()
+		if err != nil || !ok {
+			return err
+		}
 
-			data := strings.Split(split, ";")
-			id := data[0]
+		err = s.parseKey(r, batch)
+		if err != nil {
+			return err
+		}
+	}
+}
 
-			accountID, err := strconv.Atoi(data[1])
-			if err != nil {
-				log.Println("can't parse str to int")
-				return err
-			}
+func (s *Service) parseKey(r *dumpReader, batch *Batch) error {
+	createdAt, err := r.time(3, "created_at")
+	if err != nil {
+		return err
+	}
 
-			name := data[2]
+	batch.Keys = append(batch.Keys, &types.IdempotencyKey{
+		Key:         r.field(0),
+		Fingerprint: r.field(1),
+		PaymentID:   r.field(2),
+		CreatedAt:   createdAt,
+	})
 
-			amount, err := strconv.Atoi(data[3])
-			if err != nil {
-				log.Println("can't parse str to int")
-				return err
-			}
+	return nil
+}
 
-			category := types.PaymentCategoty(data[4])
+func (s *Service) FindFavoriteByID(id string) (*types.Favorite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-			favorite, err := s.FindFavoriteByID(id)
-			if err != nil {
-				newFavorite := &types.Favorite{
-					ID:        id,
-					AccountID: int64(accountID),
-					Name:      name,
-					Amount:    types.Money(amount),
-					Category:  types.PaymentCategoty(category),
-				}
+	return s.repo().FindFavoriteByID(id)
+}
 
-				s.favorites = append(s.favorites, newFavorite)
-			} else {
-				favorite.AccountID = int64(accountID)
-				favorite.Name = name
-				favorite.Amount = types.Money(amount)
-				favorite.Category = category
-			}
-		}
-	} else {
-		log.Println(ErrFileNotFound.Error())
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
 
-	return nil
+	return t.UTC().Format(time.RFC3339Nano)
 }
 
-func (s *Service) FindFavoriteByID(id string) (*types.Favorite, error) {
-	for _, favorite := range s.favorites {
-		if favorite.ID == id {
-			return favorite, nil
-		}
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
 	}
 
-	return nil, ErrFavoriteNotFound
+	return time.Parse(time.RFC3339Nano, value)
 }
 
 func actionByFile(path, data string) error {
-	file, err := os.Create(path)
-	if err != nil {
-		log.Println(err)
-		return err
-	}
-
-	defer func() {
-		err = file.Close()
-		if err != nil {
-			log.Println(err)
-		}
-	}()
-
-	_, err = file.WriteString(data)
+	err := writeFileAtomic(path, []byte(data))
 	if err != nil {
 		log.Println(err)
 		return err
@@ -582,11 +1198,7 @@ func (s *Service) HistoryToFiles(payments []types.Payment, dir string, records i
 	if len(payments) <= records {
 		result := ""
 		for _, payment := range payments {
-			result += payment.ID + ";"
-			result += strconv.Itoa(int(payment.AccountID)) + ";"
-			result += strconv.Itoa(int(payment.Amount)) + ";"
-			result += string(payment.Category) + ";"
-			result += string(payment.Status) + "\n"
+			result += formatLine(paymentRecord(payment)...)
 		}
 
 		err := actionByFile(dir+"/payments.dump", result)
@@ -600,11 +1212,7 @@ func (s *Service) HistoryToFiles(payments []types.Payment, dir string, records i
 	result := ""
 	k := 1
 	for i, payment := range payments {
-		result += payment.ID + ";"
-		result += strconv.Itoa(int(payment.AccountID)) + ";"
-		result += strconv.Itoa(int(payment.Amount)) + ";"
-		result += string(payment.Category) + ";"
-		result += string(payment.Status) + "\n"
+		result += formatLine(paymentRecord(payment)...)
 
 		if (i+1)%records == 0 {
 			err := actionByFile(dir+"/payments"+strconv.Itoa(k)+".dump", result)
@@ -626,38 +1234,54 @@ func (s *Service) HistoryToFiles(payments []types.Payment, dir string, records i
 	return nil
 }
 
-func (s *Service) SumPayments(goroutines int) types.Money {
+// SumPayments returns the total of payments in each currency.
+func (s *Service) SumPayments(goroutines int) map[types.Currency]types.Money {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summ := make(map[types.Currency]types.Money)
+	payments, err := s.repo().Payments()
+	if err != nil {
+		log.Print(err)
+		return summ
+	}
+
 	wg := sync.WaitGroup{}
 	mu := sync.Mutex{}
-	var summ types.Money = 0
 	if goroutines == 0 || goroutines == 1 {
 		wg.Add(1)
-		go func(payments []*types.Payment) {
+		go func(payments []types.Payment) {
 			defer wg.Done()
 			for _, payment := range payments {
-				summ += payment.Amount
+				if payment.Type != types.PaymentTypeTransferIn {
+					summ[payment.Currency] += payment.Amount - payment.Refunded
+				}
 			}
-		}(s.payments)
+		}(payments)
 	} else {
 		from := 0
-		count := len(s.payments) / goroutines
+		count := len(payments) / goroutines
 		for i := 1; i <= goroutines; i++ {
 			wg.Add(1)
-			last := len(s.payments) - i*count
+			last := len(payments) - i*count
 			if i == goroutines {
 				last = 0
 			}
-			to := len(s.payments) - last
-			go func(payments []*types.Payment) {
+			to := len(payments) - last
+			go func(payments []types.Payment) {
 				defer wg.Done()
-				s := types.Money(0)
+				s := make(map[types.Currency]types.Money)
 				for _, payment := range payments {
-					s += payment.Amount
+					if payment.Type != types.PaymentTypeTransferIn {
+						s[payment.Currency] += payment.Amount - payment.Refunded
+					}
 				}
 				mu.Lock()
 				defer mu.Unlock()
-				summ += s
-			}(s.payments[from:to])
+				for currency, amount := range s {
+					summ[currency] += amount
+				}
+			}(payments[from:to])
 			from += count
 		}
 	}
@@ -667,103 +1291,82 @@ func (s *Service) SumPayments(goroutines int) types.Money {
 	return summ
 }
 
-func (s *Service) FilterPayments(accountID int64, goroutines int) ([]types.Payment, error) {
-	filteredPayments := []types.Payment{}
-	wg := sync.WaitGroup{}
-	mu := sync.Mutex{}
-	if goroutines == 0 || goroutines == 1 {
-		wg.Add(1)
-		go func(payments []*types.Payment) {
-			defer wg.Done()
-			for _, payment := range payments {
-				if payment.AccountID == accountID {
-					filteredPayments = append(filteredPayments, types.Payment{
-						ID:        payment.ID,
-						AccountID: payment.AccountID,
-						Amount:    payment.Amount,
-						Category:  payment.Category,
-						Status:    payment.Status,
-					})
-				}
-			}
-		}(s.payments)
-	} else {
-		from := 0
-		count := len(s.payments) / goroutines
-		for i := 1; i <= goroutines; i++ {
-			wg.Add(1)
-			last := len(s.payments) - i*count
-			if i == goroutines {
-				last = 0
-			}
-			to := len(s.payments) - last
-			go func(payments []*types.Payment) {
-				defer wg.Done()
-				separetePayments := []types.Payment{}
-				for _, payment := range payments {
-					if payment.AccountID == accountID {
-						separetePayments = append(separetePayments, types.Payment{
-							ID:        payment.ID,
-							AccountID: payment.AccountID,
-							Amount:    payment.Amount,
-							Category:  payment.Category,
-							Status:    payment.Status,
-						})
-					}
-				}
-				mu.Lock()
-				defer mu.Unlock()
-				filteredPayments = append(filteredPayments, separetePayments...)
-			}(s.payments[from:to])
-			from += count
-		}
+// AccountPayments returns payments of the account. Unlike FilterPayments
+// it returns an empty slice for an account without payments and
+// ErrAccountNotFound only for a missing account.
+func (s *Service) AccountPayments(accountID int64) ([]types.Payment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, err := s.repo().FindAccountByID(accountID)
+	if err != nil {
+		return nil, err
 	}
 
-	wg.Wait()
+	return s.repo().FindPaymentsByAccountID(accountID)
+}
 
-	if len(filteredPayments) == 0 {
+// FilterPayments returns payments of the account. The goroutines argument
+// is kept for compatibility, the lookup goes through the repository index.
+func (s *Service) FilterPayments(accountID int64, goroutines int) ([]types.Payment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	payments, err := s.repo().FindPaymentsByAccountID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payments) == 0 {
 		return nil, ErrAccountNotFound
 	}
 
-	return filteredPayments, nil
+	return payments, nil
 }
 
 func (s *Service) FilterPaymentsByFn(filter func(payment types.Payment) bool, goroutines int) ([]types.Payment, error) {
+	s.mu.RLock()
+	payments, err := s.repo().Payments()
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
 	filteredPayments := []types.Payment{}
 	wg := sync.WaitGroup{}
 	mu := sync.Mutex{}
 	if goroutines == 0 || goroutines == 1 {
 		wg.Add(1)
-		go func(payments []*types.Payment) {
+		go func(payments []types.Payment) {
 			defer wg.Done()
 			for _, payment := range payments {
-				if filter(*payment) {
-					filteredPayments = append(filteredPayments, *payment)
+				if filter(payment) {
+					filteredPayments = append(filteredPayments, payment)
 				}
 			}
-		}(s.payments)
+		}(payments)
 	} else {
 		from := 0
-		count := len(s.payments) / goroutines
+		count := len(payments) / goroutines
 		for i := 1; i <= goroutines; i++ {
 			wg.Add(1)
-			last := len(s.payments) - i*count
+			last := len(payments) - i*count
 			if i == goroutines {
 				last = 0
 			}
-			to := len(s.payments) - last
-			go func(payments []*types.Payment) {
+			to := len(payments) - last
+			go func(payments []types.Payment) {
 				defer wg.Done()
 				separetePayments := []types.Payment{}
 				for _, payment := range payments {
-					if filter(*payment) {
-						separetePayments = append(separetePayments, *payment)
+					if filter(payment) {
+						separetePayments = append(separetePayments, payment)
 					}
 				}
 				mu.Lock()
 				defer mu.Unlock()
 				filteredPayments = append(filteredPayments, separetePayments...)
-			}(s.payments[from:to])
+			}(payments[from:to])
 			from += count
 		}
 	}
@@ -777,33 +1380,55 @@ func (s *Service) FilterPaymentsByFn(filter func(payment types.Payment) bool, go
 	return filteredPayments, nil
 }
 
-func (s *Service) SumPaymentsWithProgress() <- chan types.Progress {
+// SumPaymentsWithProgress sums payments in parts of each currency and
+// sends the result of every part to the channel.
+func (s *Service) SumPaymentsWithProgress() <-chan types.Progress {
 	size := 100_0000
 
-	amountOfMoney := make([]types.Money, 0)
-	for _, pay := range s.payments {
-		amountOfMoney = append(amountOfMoney, pay.Amount)
+	s.mu.RLock()
+	payments, err := s.repo().Payments()
+	s.mu.RUnlock()
+	if err != nil {
+		log.Print(err)
 	}
 
-	wg := sync.WaitGroup{}
-	goroutines := (len(amountOfMoney) + 1) / size
-	ch := make(chan types.Progress)
-	if goroutines <= 0 {
-		goroutines = 1
+	amountOfMoney := make(map[types.Currency][]types.Money)
+	currencies := []types.Currency{}
+	for _, pay := range payments {
+		if pay.Type != types.PaymentTypeTransferIn {
+			if _, ok := amountOfMoney[pay.Currency]; !ok {
+				currencies = append(currencies, pay.Currency)
+			}
+			amountOfMoney[pay.Currency] = append(amountOfMoney[pay.Currency], pay.Amount-pay.Refunded)
+		}
 	}
-	for i := 0; i < goroutines; i++ {
-		wg.Add(1)
-		go func(ch chan<- types.Progress, amountOfMoney []types.Money, part int) {
-			sum := 0
-			defer wg.Done()
-			for _, val := range amountOfMoney {
-				sum += int(val)
 
+	wg := sync.WaitGroup{}
+	ch := make(chan types.Progress)
+	part := 0
+	for _, currency := range currencies {
+		amounts := amountOfMoney[currency]
+		for from := 0; from < len(amounts); from += size {
+			to := from + size
+			if to > len(amounts) {
+				to = len(amounts)
 			}
-			ch <- types.Progress{
-				Result: types.Money(sum),
-			}
-		}(ch, amountOfMoney, i)
+
+			wg.Add(1)
+			go func(ch chan<- types.Progress, amountOfMoney []types.Money, part int, currency types.Currency) {
+				sum := 0
+				defer wg.Done()
+				for _, val := range amountOfMoney {
+					sum += int(val)
+				}
+				ch <- types.Progress{
+					Part:     part,
+					Result:   types.Money(sum),
+					Currency: currency,
+				}
+			}(ch, amounts[from:to], part, currency)
+			part++
+		}
 	}
 
 	go func() {
@@ -813,3 +1438,19 @@ func (s *Service) SumPaymentsWithProgress() <- chan types.Progress {
 
 	return ch
 }
+
+func copyAccount(account *types.Account) *types.Account {
+	result := *account
+	result.Limits = copyLimits(account.Limits)
+	return &result
+}
+
+func copyPayment(payment *types.Payment) *types.Payment {
+	result := *payment
+	return &result
+}
+
+func copyFavorite(favorite *types.Favorite) *types.Favorite {
+	result := *favorite
+	return &result
+}
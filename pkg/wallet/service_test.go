@@ -2,7 +2,11 @@ package wallet
 
 import (
 	"fmt"
+	"math/rand"
 	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/AlisherGulomzoda/wallet/pkg/types"
@@ -149,6 +153,26 @@ func TestService_FindPaymentByID_fail(t *testing.T) {
 	}
 }
 
+func TestService_AccountPayments(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payments, err := s.AccountPayments(account.ID)
+	if err != nil || payments == nil || len(payments) != 0 {
+		t.Errorf("invalid payments of an account without payments: %v, %v", payments, err)
+		return
+	}
+
+	_, err = s.AccountPayments(account.ID + 1)
+	if err != ErrAccountNotFound {
+		t.Errorf("must return ErrAccountNotFound: %v", err)
+	}
+}
+
 func TestService_Reject_success(t *testing.T) {
 	s := newTestService()
 	_, payments, err := s.addAccount(defaultTestAccount)
@@ -320,16 +344,23 @@ func TestService_PayFromFavorite_rules(t *testing.T) {
 }
 
 func Benchmark_SumPayments(b *testing.B) {
-	svc := &Service{}
+	repo := NewMemoryRepository()
+	svc := NewService(repo)
 
+	batch := &Batch{}
 	for i := 0; i < 103; i++ {
-		svc.payments = append(svc.payments, &types.Payment{Amount: 1})
+		batch.Payments = append(batch.Payments, &types.Payment{ID: strconv.Itoa(i), Amount: 1, Currency: types.CurrencyTJS})
+	}
+
+	err := repo.Save(batch)
+	if err != nil {
+		b.Fatal(err)
 	}
 
 	result := 103
 
 	for i := 0; i < b.N; i++ {
-		sum := svc.SumPayments(result)
+		sum := svc.SumPayments(result)[types.CurrencyTJS]
 		if result != int(sum) {
 			b.Fatalf("invalid result, got %v, want %v", sum, result)
 		}
@@ -337,14 +368,22 @@ func Benchmark_SumPayments(b *testing.B) {
 }
 
 func Benchmark_FilterPayments(b *testing.B) {
-	svc := &Service{}
+	repo := NewMemoryRepository()
+	svc := NewService(repo)
 
 	account, err := svc.RegisterAccount("+992000000000")
 	if err != nil {
 		b.Error(err)
 	}
+
+	batch := &Batch{}
 	for i := 0; i < 103; i++ {
-		svc.payments = append(svc.payments, &types.Payment{AccountID: account.ID, Amount: 1})
+		batch.Payments = append(batch.Payments, &types.Payment{ID: strconv.Itoa(i), AccountID: account.ID, Amount: 1})
+	}
+
+	err = repo.Save(batch)
+	if err != nil {
+		b.Fatal(err)
 	}
 
 	result := 103
@@ -362,10 +401,17 @@ func Benchmark_FilterPayments(b *testing.B) {
 }
 
 func Benchmark_FilterPaymentsByFn(b *testing.B) {
-	svc := &Service{}
+	repo := NewMemoryRepository()
+	svc := NewService(repo)
 
+	batch := &Batch{}
 	for i := 0; i < 103; i++ {
-		svc.payments = append(svc.payments, &types.Payment{Amount: 1})
+		batch.Payments = append(batch.Payments, &types.Payment{ID: strconv.Itoa(i), Amount: 1})
+	}
+
+	err := repo.Save(batch)
+	if err != nil {
+		b.Fatal(err)
 	}
 
 	result := 103
@@ -389,3 +435,143 @@ func Benchmark_FilterPaymentsByFn(b *testing.B) {
 		}
 	}
 }
+
+func TestService_Pay_concurrent(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992000000001", 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+	succeeded := 0
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Pay(account.ID, 1, "auto")
+			if err == ErrBalanceNotAmount {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 100 {
+		t.Errorf("invalid result: got %v successful payments, want %v", succeeded, 100)
+	}
+
+	savedAccount, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if savedAccount.Balance != 0 {
+		t.Errorf("invalid balance: got %v, want %v", savedAccount.Balance, 0)
+	}
+}
+
+func TestService_concurrent_moneyConserved(t *testing.T) {
+	s := newTestService()
+
+	const (
+		accounts   = 10
+		goroutines = 50
+		operations = 200
+	)
+
+	var deposited int64
+	ids := make([]int64, accounts)
+	for i := range ids {
+		account, err := s.addAccountWithBalance(types.Phone(fmt.Sprintf("+9920000000%02d", i)), 1_000)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ids[i] = account.ID
+		deposited += 1_000
+	}
+
+	wg := sync.WaitGroup{}
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(int64(g)))
+			var payments []string
+			for i := 0; i < operations; i++ {
+				accountID := ids[rnd.Intn(len(ids))]
+				switch rnd.Intn(4) {
+				case 0:
+					amount := types.Money(rnd.Intn(10) + 1)
+					if err := s.Deposit(accountID, amount); err != nil {
+						t.Error(err)
+						return
+					}
+					atomic.AddInt64(&deposited, int64(amount))
+				case 1:
+					if len(payments) == 0 {
+						continue
+					}
+					if err := s.Reject(payments[rnd.Intn(len(payments))]); err != nil {
+						t.Error(err)
+						return
+					}
+				case 2:
+					if _, err := s.FindAccountByID(accountID); err != nil {
+						t.Error(err)
+						return
+					}
+				default:
+					payment, err := s.Pay(accountID, types.Money(rnd.Intn(50)+1), "auto")
+					if err == ErrBalanceNotAmount {
+						continue
+					}
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					payments = append(payments, payment.ID)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	total := s.SumPayments(1)[DefaultCurrency]
+	for _, id := range ids {
+		account, err := s.FindAccountByID(id)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if account.Balance < 0 {
+			t.Errorf("negative balance on account %v: %v", id, account.Balance)
+		}
+		total += account.Balance
+	}
+
+	if int64(total) != atomic.LoadInt64(&deposited) {
+		t.Errorf("money not conserved: got %v, want %v", total, deposited)
+	}
+
+	mismatches, err := s.Reconcile()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(mismatches) != 0 {
+		t.Errorf("ledger does not match balances: %v", mismatches)
+	}
+}
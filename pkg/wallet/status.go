@@ -0,0 +1,100 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var ErrInvalidTransition = errors.New("invalid payment status transition")
+
+// TransitionError is returned when a payment can't move to the requested
+// status. It matches ErrInvalidTransition with errors.Is.
+type TransitionError struct {
+	PaymentID string
+	From      types.PaymentStatus
+	To        types.PaymentStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("payment %s: can't move from %s to %s", e.PaymentID, e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+var paymentTransitions = map[types.PaymentStatus][]types.PaymentStatus{
+	types.PaymentStatusInProgress: {
+		types.PaymentStatusOk,
+		types.PaymentStatusFail,
+		types.PaymentStatusPartiallyRefunded,
+		types.PaymentStatusRefunded,
+	},
+	types.PaymentStatusOk: {
+		types.PaymentStatusPartiallyRefunded,
+		types.PaymentStatusRefunded,
+	},
+	types.PaymentStatusPartiallyRefunded: {
+		types.PaymentStatusRefunded,
+	},
+}
+
+// transition moves the payment to the status. Moving to the current status
+// is not an error and reports false, so the caller can skip side effects.
+func transition(payment *types.Payment, to types.PaymentStatus) (bool, error) {
+	if payment.Status == to {
+		return false, nil
+	}
+
+	for _, status := range paymentTransitions[payment.Status] {
+		if status == to {
+			payment.Status = to
+			return true, nil
+		}
+	}
+
+	return false, &TransitionError{PaymentID: payment.ID, From: payment.Status, To: to}
+}
+
+func (s *Service) Confirm(paymentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, err := s.repo().FindPaymentByID(paymentID)
+	if err != nil {
+		return err
+	}
+
+	payments := []*types.Payment{payment}
+	if isTransfer(payment) {
+		outgoing, incoming, err := s.findTransfer(payment)
+		if err != nil {
+			return err
+		}
+		payments = []*types.Payment{outgoing, incoming}
+	}
+
+	batch := &Batch{}
+	for _, payment := range payments {
+		ok, err := transition(payment, types.PaymentStatusOk)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			batch.Payments = append(batch.Payments, payment)
+			batch.Events = append(batch.Events, &PaymentConfirmed{
+				EventHeader: EventHeader{AccountID: payment.AccountID},
+				PaymentID:   payment.ID,
+			})
+		}
+	}
+
+	if len(batch.Payments) == 0 {
+		return nil
+	}
+
+	return s.save(batch)
+}
@@ -0,0 +1,147 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+	"github.com/google/uuid"
+)
+
+func TestService_Confirm_success(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment := payments[0]
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	saved, err := s.FindPaymetByID(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if saved.Status != types.PaymentStatusOk {
+		t.Errorf("invalid status: got %v, want %v", saved.Status, types.PaymentStatusOk)
+	}
+}
+
+func TestService_Confirm_fail(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Confirm(uuid.New().String())
+	if err != ErrPaymentNotFound {
+		t.Error(err)
+		return
+	}
+
+	payment := payments[0]
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Confirm(payment.ID)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Error(err)
+		return
+	}
+
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) || transitionErr.From != types.PaymentStatusFail || transitionErr.To != types.PaymentStatusOk {
+		t.Errorf("invalid transition error: %v", err)
+	}
+}
+
+func TestService_Reject_confirmed(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payment := payments[0]
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(payment.ID)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Error(err)
+	}
+}
+
+func TestService_Reject_idempotent(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		err = s.Reject(payments[0].ID)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	saved, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if saved.Balance != defaultTestAccount.balance {
+		t.Errorf("invalid balance: got %v, want %v", saved.Balance, defaultTestAccount.balance)
+	}
+}
+
+func TestService_Confirm_transfer(t *testing.T) {
+	s := newTestService()
+	from, to, err := s.addTransferAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	outgoing, err := s.Transfer(from.ID, to.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Confirm(outgoing.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	incoming, err := s.FindPaymetByID(outgoing.LinkedID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if incoming.Status != types.PaymentStatusOk {
+		t.Errorf("invalid status: got %v, want %v", incoming.Status, types.PaymentStatusOk)
+	}
+}
@@ -0,0 +1,267 @@
+package wallet
+
+import (
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+	"github.com/google/uuid"
+)
+
+const TransferCategory types.PaymentCategoty = "transfer"
+
+// Transfer moves amount between two accounts. Both sides get a payment
+// record linked to each other; the outgoing one is returned. Rejecting
+// any of the two records reverses the whole transfer.
+func (s *Service) Transfer(fromID, toID int64, amount types.Money, options ...PayOption) (*types.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.transfer(fromID, toID, amount, newPayOptions(options))
+}
+
+func (s *Service) transfer(fromID, toID int64, amount types.Money, o *payOptions) (*types.Payment, error) {
+	if fromID == toID {
+		return nil, ErrTransferToSameAccount
+	}
+
+	request := fingerprint("transfer", fromID, toID, amount, o.currency)
+	done, err := s.replay(o.key, request)
+	if err != nil {
+		return nil, err
+	}
+	if done != nil {
+		return s.repo().FindPaymentByID(done.PaymentID)
+	}
+
+	from, err := s.repo().FindAccountByID(fromID)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := s.repo().FindAccountByID(toID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkActive(from, "transfer")
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkActive(to, "transfer")
+	if err != nil {
+		return nil, err
+	}
+
+	requested := cashOf(from, amount, o)
+	sent, sentRate, err := s.convert(requested, from.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkPayment(from, sent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkLimits(from, TransferCategory, sent)
+	if err != nil {
+		return nil, err
+	}
+
+	received, receivedRate, err := s.convert(requested, to.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	outgoing := &types.Payment{
+		ID:        uuid.New().String(),
+		AccountID: from.ID,
+		Amount:    sent.Amount,
+		Category:  TransferCategory,
+		Status:    types.PaymentStatusInProgress,
+		Type:      types.PaymentTypeTransferOut,
+		Currency:  sent.Currency,
+	}
+	incoming := &types.Payment{
+		ID:        uuid.New().String(),
+		AccountID: to.ID,
+		Amount:    received.Amount,
+		Category:  TransferCategory,
+		Status:    types.PaymentStatusInProgress,
+		Type:      types.PaymentTypeTransferIn,
+		Currency:  received.Currency,
+	}
+	outgoing.LinkedID = incoming.ID
+	incoming.LinkedID = outgoing.ID
+	setOriginal(outgoing, requested, sentRate)
+	setOriginal(incoming, requested, receivedRate)
+
+	before := from.Balance
+	err = debit(from, sent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = credit(to, received)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.save(&Batch{
+		Accounts: []*types.Account{from, to},
+		Payments: []*types.Payment{outgoing, incoming},
+		Postings: s.transferPostings(from.ID, to.ID, sent, received, outgoing.ID),
+		Keys:     keysOf(o.key, request, outgoing.ID),
+		Events:   []Event{paymentCreated(outgoing, from), paymentCreated(incoming, to)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify("Account %d: transfer %d %s to account %d, balance %d %s", from.ID, sent.Amount, sent.Currency, to.ID, from.Balance, from.Currency)
+	s.notify("Account %d: transfer %d %s from account %d, balance %d %s", to.ID, received.Amount, received.Currency, from.ID, to.Balance, to.Currency)
+	s.notifyLowBalance(from, before)
+
+	return outgoing, nil
+}
+
+func isTransfer(payment *types.Payment) bool {
+	return payment.Type == types.PaymentTypeTransferOut || payment.Type == types.PaymentTypeTransferIn
+}
+
+// findTransfer returns the outgoing and the incoming records of a transfer.
+func (s *Service) findTransfer(payment *types.Payment) (*types.Payment, *types.Payment, error) {
+	linked, err := s.repo().FindPaymentByID(payment.LinkedID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if payment.Type == types.PaymentTypeTransferIn {
+		return linked, payment, nil
+	}
+
+	return payment, linked, nil
+}
+
+func (s *Service) refundTransfer(payment *types.Payment, amount types.Money, status types.PaymentStatus, operation string) error {
+	outgoing, incoming, err := s.findTransfer(payment)
+	if err != nil {
+		return err
+	}
+
+	_, err = transition(outgoing, status)
+	if err != nil {
+		return err
+	}
+
+	_, err = transition(incoming, status)
+	if err != nil {
+		return err
+	}
+
+	if amount > outgoing.Amount-outgoing.Refunded {
+		return ErrRefundExceedsAmount
+	}
+
+	from, err := s.repo().FindAccountByID(outgoing.AccountID)
+	if err != nil {
+		return err
+	}
+
+	to, err := s.repo().FindAccountByID(incoming.AccountID)
+	if err != nil {
+		return err
+	}
+
+	err = checkActive(from, operation)
+	if err != nil {
+		return err
+	}
+
+	err = checkActive(to, operation)
+	if err != nil {
+		return err
+	}
+
+	returned := types.Cash{Amount: amount, Currency: outgoing.Currency}
+	taken := types.Cash{Amount: incoming.Amount - incoming.Refunded, Currency: incoming.Currency}
+	if amount < outgoing.Amount-outgoing.Refunded {
+		// the incoming side is refunded in the same proportion as it was
+		// received, never more than is left of it
+		part := scale(amount, incoming.Amount, outgoing.Amount, s.rounding)
+		if part < taken.Amount {
+			taken.Amount = part
+		}
+	}
+
+	postings := s.transferPostings(to.ID, from.ID, taken, returned, outgoing.ID)
+	before := to.Balance
+	if taken.Amount == 0 {
+		// A part of a transfer in another currency may round to nothing,
+		// the exchange returns it alone then. The last refund takes the
+		// rest of the incoming side.
+		postings = postings[1:]
+	} else {
+		err = checkPayment(to, taken)
+		if err != nil {
+			return err
+		}
+
+		err = debit(to, taken)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = credit(from, returned)
+	if err != nil {
+		return err
+	}
+
+	outgoing.Refunded += returned.Amount
+	incoming.Refunded += taken.Amount
+
+	err = s.save(&Batch{
+		Accounts: []*types.Account{from, to},
+		Payments: []*types.Payment{outgoing, incoming},
+		Postings: postings,
+		Events: []Event{
+			paymentReturned(outgoing, from, returned.Amount, status),
+			paymentReturned(incoming, to, taken.Amount, status),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	action := returnAction(status)
+	s.notify("Account %d: transfer %s %s, %d %s returned, balance %d %s", from.ID, outgoing.ID, action, returned.Amount, returned.Currency, from.Balance, from.Currency)
+	if taken.Amount != 0 {
+		s.notify("Account %d: transfer %s %s, %d %s taken back, balance %d %s", to.ID, incoming.ID, action, taken.Amount, taken.Currency, to.Balance, to.Currency)
+		s.notifyLowBalance(to, before)
+	}
+
+	return nil
+}
+
+func (s *Service) repeatTransfer(payment *types.Payment) (*types.Payment, error) {
+	outgoing, incoming, err := s.findTransfer(payment)
+	if err != nil {
+		return nil, err
+	}
+
+	original := originalOf(outgoing)
+	return s.transfer(outgoing.AccountID, incoming.AccountID, original.Amount, &payOptions{currency: original.Currency})
+}
+
+// transferPostings moves sent from one account to another which receives
+// received. Money in different currencies goes through the exchange.
+func (s *Service) transferPostings(fromID, toID int64, sent, received types.Cash, paymentID string) []*types.Posting {
+	if sent.Currency == received.Currency {
+		return []*types.Posting{s.newPosting(fromID, toID, sent, paymentID)}
+	}
+
+	return []*types.Posting{
+		s.newPosting(fromID, ExchangeAccountID, sent, paymentID),
+		s.newPosting(ExchangeAccountID, toID, received, paymentID),
+	}
+}
@@ -0,0 +1,170 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func (t *testService) addTransferAccounts() (*types.Account, *types.Account, error) {
+	from, err := t.addAccountWithBalance("+992000000001", 1_000)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	to, err := t.RegisterAccount("+992000000002")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return from, to, nil
+}
+
+func (t *testService) balance(accountID int64) types.Money {
+	account, err := t.FindAccountByID(accountID)
+	if err != nil {
+		return -1
+	}
+
+	return account.Balance
+}
+
+func TestService_Transfer_success(t *testing.T) {
+	s := newTestService()
+	from, to, err := s.addTransferAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	outgoing, err := s.Transfer(from.ID, to.ID, 300)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	incoming, err := s.FindPaymetByID(outgoing.LinkedID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if incoming.AccountID != to.ID || incoming.LinkedID != outgoing.ID || incoming.Type != types.PaymentTypeTransferIn {
+		t.Errorf("invalid incoming payment: %v", incoming)
+		return
+	}
+
+	if s.balance(from.ID) != 700 || s.balance(to.ID) != 300 {
+		t.Errorf("invalid balances: from - %v, to - %v", s.balance(from.ID), s.balance(to.ID))
+		return
+	}
+
+	mismatches, err := s.Reconcile()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(mismatches) != 0 {
+		t.Errorf("unexpected mismatches: %v", mismatches)
+	}
+}
+
+func TestService_Transfer_fail(t *testing.T) {
+	s := newTestService()
+	from, to, err := s.addTransferAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Transfer(from.ID, to.ID, 0)
+	if err != ErrAmountMustGreateZero {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Transfer(from.ID, to.ID, 1_001)
+	if err != ErrBalanceNotAmount {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Transfer(from.ID, from.ID, 1)
+	if err != ErrTransferToSameAccount {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Transfer(from.ID, to.ID+1, 1)
+	if err != ErrAccountNotFound {
+		t.Error(err)
+	}
+}
+
+func TestService_Transfer_reject(t *testing.T) {
+	s := newTestService()
+	from, to, err := s.addTransferAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	outgoing, err := s.Transfer(from.ID, to.ID, 300)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(outgoing.LinkedID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s.balance(from.ID) != 1_000 || s.balance(to.ID) != 0 {
+		t.Errorf("invalid balances: from - %v, to - %v", s.balance(from.ID), s.balance(to.ID))
+		return
+	}
+
+	saved, err := s.FindPaymetByID(outgoing.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if saved.Status != types.PaymentStatusFail {
+		t.Errorf("outgoing payment must be rejected: %v", saved)
+	}
+}
+
+func TestService_Transfer_rejectSpent(t *testing.T) {
+	s := newTestService()
+	from, to, err := s.addTransferAccounts()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	outgoing, err := s.Transfer(from.ID, to.ID, 300)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.Pay(to.ID, 100, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(outgoing.ID)
+	if err != ErrBalanceNotAmount {
+		t.Error(err)
+		return
+	}
+
+	if s.balance(from.ID) != 700 || s.balance(to.ID) != 200 {
+		t.Errorf("invalid balances: from - %v, to - %v", s.balance(from.ID), s.balance(to.ID))
+	}
+}
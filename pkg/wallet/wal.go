@@ -0,0 +1,551 @@
+package wallet
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+var (
+	ErrCorruptSnapshot = errors.New("snapshot is corrupt")
+	ErrCorruptLog      = errors.New("log is corrupt")
+)
+
+// errTornRecord is returned for a record which runs past the end of the
+// data.
+var errTornRecord = errors.New("torn record")
+
+const (
+	walFile      = "wallet.wal"
+	snapshotFile = "wallet.snapshot"
+
+	// walHeaderSize is the size of the length and the checksum which
+	// precede every record.
+	walHeaderSize = 8
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// walRecord is a change saved to the log, or the whole state saved to a
+// snapshot. Seq numbers the records of the log, a snapshot keeps the Seq
+// of the last record it contains and the seq of the last trimmed outbox
+// entry.
+type walRecord struct {
+	Seq        int64
+	OutboxBase int64                   `json:",omitempty"`
+	Accounts   []*types.Account        `json:",omitempty"`
+	Payments   []*types.Payment        `json:",omitempty"`
+	Favorites  []*types.Favorite       `json:",omitempty"`
+	Postings   []*types.Posting        `json:",omitempty"`
+	Keys       []*types.IdempotencyKey `json:",omitempty"`
+	Events     []walEvent              `json:",omitempty"`
+	Cursors    map[string]int64        `json:",omitempty"`
+}
+
+type walEvent struct {
+	Name  string
+	Event json.RawMessage
+}
+
+// FileRepository keeps records in memory like MemoryRepository and makes
+// them durable in a dir. Every change is appended to a checksummed log and
+// synced to disk before it is applied. The log is replayed on open, on top
+// of the last snapshot, and is emptied after every snapshot.
+type FileRepository struct {
+	*MemoryRepository
+
+	mu            sync.Mutex
+	dir           string
+	log           *os.File
+	size          int64
+	seq           int64
+	records       int
+	snapshotEvery int
+}
+
+type FileRepositoryOption func(r *FileRepository)
+
+// WithSnapshotEvery takes a snapshot after every records changes saved to
+// the log. Zero disables automatic snapshots.
+func WithSnapshotEvery(records int) FileRepositoryOption {
+	return func(r *FileRepository) {
+		r.snapshotEvery = records
+	}
+}
+
+// WithOutboxRetention keeps the last records outbox entries for a replay
+// after every relay has delivered them. DefaultOutboxRetention is kept
+// by default.
+func WithOutboxRetention(records int) FileRepositoryOption {
+	return func(r *FileRepository) {
+		r.outboxRetention = records
+	}
+}
+
+// OpenFileRepository loads the records kept in the dir, creating the dir
+// when it doesn't exist. A torn record at the end of the log, left by a
+// crash in the middle of a write, is dropped. A bad record followed by
+// other records fails with ErrCorruptLog and the log is left as it is.
+func OpenFileRepository(dir string, options ...FileRepositoryOption) (*FileRepository, error) {
+	r := &FileRepository{
+		MemoryRepository: NewMemoryRepository(),
+		dir:              dir,
+		snapshotEvery:    10_000,
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.loadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.replay()
+	if err != nil {
+		return nil, err
+	}
+
+	r.log, err = os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *FileRepository) Save(batch *Batch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record := &walRecord{
+		Accounts:  batch.Accounts,
+		Payments:  batch.Payments,
+		Favorites: batch.Favorites,
+		Postings:  batch.Postings,
+		Keys:      batch.Keys,
+	}
+
+	var err error
+	record.Events, err = encodeEvents(batch.Events)
+	if err != nil {
+		return err
+	}
+
+	err = r.append(record)
+	if err != nil {
+		return err
+	}
+
+	err = r.MemoryRepository.Save(batch)
+	if err != nil {
+		return err
+	}
+
+	r.snapshotIfDue()
+
+	return nil
+}
+
+func (r *FileRepository) SaveOutboxCursor(relay string, seq int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.append(&walRecord{Cursors: map[string]int64{relay: seq}})
+	if err != nil {
+		return err
+	}
+
+	err = r.MemoryRepository.SaveOutboxCursor(relay, seq)
+	if err != nil {
+		return err
+	}
+
+	r.snapshotIfDue()
+
+	return nil
+}
+
+// Snapshot saves all records to a new snapshot and empties the log.
+func (r *FileRepository) Snapshot() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.snapshot()
+}
+
+// Close closes the log. The repository can't be used after Close.
+func (r *FileRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.log.Close()
+}
+
+// append writes the record to the end of the log and syncs it.
+func (r *FileRepository) append(record *walRecord) error {
+	record.Seq = r.seq + 1
+
+	data, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.log.Write(data)
+	if err == nil {
+		err = r.log.Sync()
+	}
+	if err != nil {
+		// A torn record would hide the records appended after it.
+		truncateErr := r.log.Truncate(r.size)
+		if truncateErr != nil {
+			log.Printf("wal: can't cut a failed write: %v", truncateErr)
+		}
+		return err
+	}
+
+	r.size += int64(len(data))
+	r.seq = record.Seq
+	r.records++
+
+	return nil
+}
+
+// snapshotIfDue takes a snapshot after enough records. The change is in
+// the log already, so a failed snapshot doesn't fail it: the error is
+// logged and the snapshot is tried again after the next change.
+func (r *FileRepository) snapshotIfDue() {
+	if r.snapshotEvery == 0 || r.records < r.snapshotEvery {
+		return
+	}
+
+	err := r.snapshot()
+	if err != nil {
+		log.Printf("wal: %s: snapshot failed: %v", r.dir, err)
+	}
+}
+
+func (r *FileRepository) snapshot() error {
+	record, err := r.state()
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomic(filepath.Join(r.dir, snapshotFile), data)
+	if err != nil {
+		return err
+	}
+
+	// The records of the log are in the snapshot now. A crash before the
+	// log is emptied is harmless, replay skips them by Seq.
+	err = r.log.Truncate(0)
+	if err != nil {
+		return err
+	}
+
+	err = r.log.Sync()
+	if err != nil {
+		return err
+	}
+
+	r.size = 0
+	r.records = 0
+
+	return nil
+}
+
+// state makes a record of all the records of the repository.
+func (r *FileRepository) state() (*walRecord, error) {
+	m := r.MemoryRepository
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	record := &walRecord{
+		Seq:        r.seq,
+		OutboxBase: m.outboxBase,
+		Accounts:   m.accounts,
+		Payments:   m.payments,
+		Favorites:  m.favorites,
+		Keys:       m.keys,
+		Cursors:    m.cursors,
+	}
+
+	record.Postings = make([]*types.Posting, len(m.postings))
+	for i := range m.postings {
+		record.Postings[i] = &m.postings[i]
+	}
+
+	events := make([]Event, len(m.outbox))
+	for i, entry := range m.outbox {
+		events[i] = entry.Event
+	}
+
+	var err error
+	record.Events, err = encodeEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (r *FileRepository) loadSnapshot() error {
+	data, err := ioutil.ReadFile(filepath.Join(r.dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	record, size, err := decodeRecord(data)
+	if err != nil || size != len(data) {
+		return fmt.Errorf("%w: %v", ErrCorruptSnapshot, err)
+	}
+
+	r.MemoryRepository.outboxBase = record.OutboxBase
+	err = r.apply(record)
+	if err != nil {
+		return err
+	}
+
+	r.seq = record.Seq
+
+	return nil
+}
+
+// replay applies the records of the log which are not in the snapshot.
+// Only the last record may be bad: it is the one a crash could tear, and
+// the log is cut before it. A record which runs past the end of the log is
+// torn only when no valid record follows its start, otherwise its length
+// is corrupt.
+func (r *FileRepository) replay() error {
+	path := filepath.Join(r.dir, walFile)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for offset < len(data) {
+		record, size, err := decodeRecord(data[offset:])
+		torn := errors.Is(err, errTornRecord)
+		if err != nil && (!torn && offset+size < len(data) || torn && validRecordAfter(data[offset:])) {
+			return fmt.Errorf("%w: %s: record at offset %d: %v", ErrCorruptLog, path, offset, err)
+		}
+		if err != nil {
+			log.Printf("wal: %s: dropping %d bytes after offset %d: %v", path, len(data)-offset, offset, err)
+			break
+		}
+
+		if record.Seq > r.seq {
+			err = r.apply(record)
+			if err != nil {
+				return err
+			}
+
+			r.seq = record.Seq
+			r.records++
+		}
+		offset += size
+	}
+	r.size = int64(offset)
+
+	if offset == len(data) {
+		return nil
+	}
+
+	return os.Truncate(path, int64(offset))
+}
+
+func (r *FileRepository) apply(record *walRecord) error {
+	events, err := decodeEvents(record.Events)
+	if err != nil {
+		return err
+	}
+
+	err = r.MemoryRepository.Save(&Batch{
+		Accounts:  record.Accounts,
+		Payments:  record.Payments,
+		Favorites: record.Favorites,
+		Postings:  record.Postings,
+		Keys:      record.Keys,
+		Events:    events,
+	})
+	if err != nil {
+		return err
+	}
+
+	for relay, seq := range record.Cursors {
+		err = r.MemoryRepository.SaveOutboxCursor(relay, seq)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeRecord frames the JSON of the record with its length and CRC-32C
+// checksum.
+func encodeRecord(record *walRecord) ([]byte, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, walHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(data[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(data[4:8], crc32.Checksum(payload, crcTable))
+	copy(data[walHeaderSize:], payload)
+
+	return data, nil
+}
+
+// decodeRecord decodes the record at the start of data and returns its
+// size with the header. The size is returned for a bad record as well,
+// unless the record is torn.
+func decodeRecord(data []byte) (*walRecord, int, error) {
+	if len(data) < walHeaderSize {
+		return nil, 0, fmt.Errorf("%w: short header", errTornRecord)
+	}
+
+	length := int(binary.LittleEndian.Uint32(data[0:4]))
+	if len(data)-walHeaderSize < length {
+		return nil, 0, fmt.Errorf("%w: short record", errTornRecord)
+	}
+
+	size := walHeaderSize + length
+	payload := data[walHeaderSize:size]
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(data[4:8]) {
+		return nil, size, errors.New("checksum mismatch")
+	}
+
+	record := &walRecord{}
+	err := json.Unmarshal(payload, record)
+	if err != nil {
+		return nil, size, err
+	}
+
+	return record, size, nil
+}
+
+// validRecordAfter tells whether a valid record starts anywhere in data
+// after its first byte.
+func validRecordAfter(data []byte) bool {
+	for i := 1; i < len(data); i++ {
+		_, _, err := decodeRecord(data[i:])
+		if err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func encodeEvents(events []Event) ([]walEvent, error) {
+	result := make([]walEvent, len(events))
+	for i, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = walEvent{Name: event.EventName(), Event: data}
+	}
+
+	return result, nil
+}
+
+func decodeEvents(events []walEvent) ([]Event, error) {
+	result := make([]Event, len(events))
+	for i, event := range events {
+		newEvent, ok := eventTypes[event.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown event %q", event.Name)
+		}
+
+		result[i] = newEvent()
+		err := json.Unmarshal(event.Event, result[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// writeFileAtomic replaces the file with data, so that a crash leaves
+// either the old or the new content. The data is written to a temp file
+// in the same dir, synced and renamed over the file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	file, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := file.Name()
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir makes a rename in the dir durable.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = file.Sync()
+	if err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+
+	return nil
+}
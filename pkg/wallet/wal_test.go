@@ -0,0 +1,536 @@
+package wallet
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/AlisherGulomzoda/wallet/pkg/types"
+)
+
+func newWALTestDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func openTestFileRepository(t *testing.T, dir string, options ...FileRepositoryOption) (*FileRepository, *testService) {
+	repo, err := OpenFileRepository(dir, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return repo, &testService{Service: NewService(repo)}
+}
+
+// walState is everything a FileRepository must keep across a restart.
+type walState struct {
+	accounts  []types.Account
+	payments  []types.Payment
+	favorites []types.Favorite
+	postings  []types.Posting
+	keys      []types.IdempotencyKey
+	outbox    []string
+	cursor    int64
+}
+
+func walStateOf(t *testing.T, repo Repository) walState {
+	state := walState{}
+	var err error
+	state.accounts, err = repo.Accounts()
+	if err == nil {
+		state.payments, err = repo.Payments()
+	}
+	if err == nil {
+		state.favorites, err = repo.Favorites()
+	}
+	if err == nil {
+		state.postings, err = repo.Postings()
+	}
+	if err == nil {
+		state.keys, err = repo.IdempotencyKeys()
+	}
+	if err == nil {
+		state.cursor, err = repo.OutboxCursor("test")
+	}
+
+	entries, err2 := repo.OutboxEntries(0, 0)
+	if err == nil {
+		err = err2
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Times come back from the disk without a location and a monotonic
+	// reading.
+	for i := range state.accounts {
+		state.accounts[i].CreatedAt = state.accounts[i].CreatedAt.Round(0).UTC()
+		state.accounts[i].UpdatedAt = state.accounts[i].UpdatedAt.Round(0).UTC()
+	}
+	for i := range state.payments {
+		state.payments[i].CreatedAt = state.payments[i].CreatedAt.Round(0).UTC()
+		state.payments[i].UpdatedAt = state.payments[i].UpdatedAt.Round(0).UTC()
+	}
+	for i := range state.favorites {
+		state.favorites[i].CreatedAt = state.favorites[i].CreatedAt.Round(0).UTC()
+		state.favorites[i].UpdatedAt = state.favorites[i].UpdatedAt.Round(0).UTC()
+	}
+	for i := range state.postings {
+		state.postings[i].CreatedAt = state.postings[i].CreatedAt.Round(0).UTC()
+	}
+	for i := range state.keys {
+		state.keys[i].CreatedAt = state.keys[i].CreatedAt.Round(0).UTC()
+	}
+
+	for _, entry := range entries {
+		state.outbox = append(state.outbox, entry.Event.Header().ID+" "+entry.Event.EventName())
+	}
+
+	return state
+}
+
+// addWALRecords makes every kind of change of the records.
+func addWALRecords(t *testing.T, s *testService) {
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Pay(account.ID, 100, "auto", WithIdempotencyKey("order-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.FavoritePayment(payments[0].ID, "car")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Reject(payments[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.SetLimits(account.ID, types.Limits{Daily: 1_000_00, Categories: map[types.PaymentCategoty]types.Money{"auto": 500_00}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.repo().SaveOutboxCursor("test", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileRepository_reopen(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	repo, s := openTestFileRepository(t, dir)
+	addWALRecords(t, s)
+	want := walStateOf(t, repo)
+	repo.Close()
+
+	repo, s = openTestFileRepository(t, dir)
+	defer repo.Close()
+
+	got := walStateOf(t, repo)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid state after reopen:\ngot  - %+v\nwant - %+v", got, want)
+		return
+	}
+
+	account, err := s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if account.ID != 2 {
+		t.Errorf("invalid ID of a new account: %v", account.ID)
+	}
+
+	_, err = s.Pay(1, 200, "auto", WithIdempotencyKey("order-1"))
+	if err != ErrIdempotencyKeyReused {
+		t.Errorf("idempotency key forgotten: %v", err)
+	}
+
+	events, err := s.repo().OutboxEntries(0, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, ok := events[len(events)-2].Event.(*LimitsChanged); !ok {
+		t.Errorf("invalid event type: %T", events[len(events)-2].Event)
+	}
+}
+
+func TestFileRepository_tornRecord(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	repo, s := openTestFileRepository(t, dir)
+	account, err := s.addAccountWithBalance("+992000000001", 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := walStateOf(t, repo)
+
+	_, err = s.Pay(account.ID, 100, "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	// A crash in the middle of the last write.
+	path := filepath.Join(dir, walFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Truncate(path, info.Size()-10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, s = openTestFileRepository(t, dir)
+	if got := walStateOf(t, repo); !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid state after a torn write:\ngot  - %+v\nwant - %+v", got, want)
+	}
+
+	_, err = s.Pay(account.ID, 200, "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	repo, s = openTestFileRepository(t, dir)
+	defer repo.Close()
+
+	if balance := s.balance(account.ID); balance != 800 {
+		t.Errorf("invalid balance after reopen: %v", balance)
+	}
+}
+
+func TestFileRepository_checksum(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	repo, s := openTestFileRepository(t, dir)
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := walStateOf(t, repo)
+
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	path := filepath.Join(dir, walFile)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data[len(data)-2] ^= 0xff
+	err = ioutil.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, _ = openTestFileRepository(t, dir)
+	defer repo.Close()
+
+	if got := walStateOf(t, repo); !reflect.DeepEqual(got, want) {
+		t.Errorf("corrupt record applied:\ngot  - %+v\nwant - %+v", got, want)
+	}
+}
+
+func TestFileRepository_corruptRecord(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	repo, s := openTestFileRepository(t, dir, WithSnapshotEvery(0))
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		err = s.Deposit(account.ID, 1_000)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo.Close()
+
+	path := filepath.Join(dir, walFile)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A bad byte in the second record, with valid records after it.
+	_, size, err := decodeRecord(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data[size+walHeaderSize+1] ^= 0xff
+	err = ioutil.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = OpenFileRepository(dir)
+	if !errors.Is(err, ErrCorruptLog) {
+		t.Errorf("invalid error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(data)) {
+		t.Errorf("log cut from %v to %v bytes", len(data), info.Size())
+	}
+}
+
+func TestFileRepository_corruptLength(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	repo, s := openTestFileRepository(t, dir, WithSnapshotEvery(0))
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		err = s.Deposit(account.ID, 1_000)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo.Close()
+
+	path := filepath.Join(dir, walFile)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The length of the second record runs past the end of the log.
+	_, size, err := decodeRecord(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary.LittleEndian.PutUint32(data[size:size+4], 0x7fffffff)
+	err = ioutil.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = OpenFileRepository(dir)
+	if !errors.Is(err, ErrCorruptLog) {
+		t.Errorf("invalid error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(data)) {
+		t.Errorf("log cut from %v to %v bytes", len(data), info.Size())
+	}
+}
+
+func TestFileRepository_Snapshot(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	repo, s := openTestFileRepository(t, dir, WithSnapshotEvery(3))
+	addWALRecords(t, s)
+	want := walStateOf(t, repo)
+	repo.Close()
+
+	info, err := os.Stat(filepath.Join(dir, walFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() == 0 || repo.records >= 3 {
+		t.Errorf("log not compacted: %v records, %v bytes", repo.records, info.Size())
+	}
+
+	repo, _ = openTestFileRepository(t, dir, WithSnapshotEvery(0))
+	defer repo.Close()
+
+	if got := walStateOf(t, repo); !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid state after snapshot:\ngot  - %+v\nwant - %+v", got, want)
+	}
+}
+
+func TestFileRepository_Snapshot_crashBeforeCompaction(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	repo, s := openTestFileRepository(t, dir, WithSnapshotEvery(0))
+	addWALRecords(t, s)
+	want := walStateOf(t, repo)
+
+	path := filepath.Join(dir, walFile)
+	log, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = repo.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	// The snapshot is renamed but the log is not emptied yet.
+	err = ioutil.WriteFile(path, log, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, _ = openTestFileRepository(t, dir)
+	defer repo.Close()
+
+	if got := walStateOf(t, repo); !reflect.DeepEqual(got, want) {
+		t.Errorf("records replayed twice:\ngot  - %+v\nwant - %+v", got, want)
+	}
+}
+
+func TestFileRepository_Snapshot_trimmedOutbox(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	repo, s := openTestFileRepository(t, dir, WithSnapshotEvery(0), WithOutboxRetention(0))
+	_, err := s.addAccountWithBalance("+992000000001", 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = repo.SaveOutboxCursor("test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = repo.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	repo, s = openTestFileRepository(t, dir, WithOutboxRetention(0))
+	defer repo.Close()
+
+	if seqs := outboxSeqs(t, repo, 0); !reflect.DeepEqual(seqs, []int64{2}) {
+		t.Errorf("invalid kept entries: %v", seqs)
+		return
+	}
+
+	_, err = s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seqs := outboxSeqs(t, repo, 2); !reflect.DeepEqual(seqs, []int64{3}) {
+		t.Errorf("invalid seq of a new entry: %v", seqs)
+	}
+}
+
+func TestFileRepository_Snapshot_fail(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	repo, s := openTestFileRepository(t, dir, WithSnapshotEvery(1))
+	defer repo.Close()
+
+	// A dir in place of the snapshot makes the rename of a new one fail.
+	path := filepath.Join(dir, snapshotFile)
+	err := os.MkdirAll(filepath.Join(path, "busy"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Errorf("saved change reported as failed: %v", err)
+		return
+	}
+
+	if repo.records != 1 {
+		t.Errorf("failed snapshot emptied the log: %v records", repo.records)
+	}
+
+	err = os.RemoveAll(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if repo.records != 0 {
+		t.Errorf("snapshot not retried: %v records", repo.records)
+	}
+}
+
+func TestFileRepository_corruptSnapshot(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	err := ioutil.WriteFile(filepath.Join(dir, snapshotFile), []byte("garbage"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = OpenFileRepository(dir)
+	if err == nil || !errors.Is(err, ErrCorruptSnapshot) {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, cleanup := newWALTestDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "accounts.dump")
+	for _, data := range []string{"old", "new"} {
+		err := writeFileAtomic(path, []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Errorf("invalid content: %q, %v", data, err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Errorf("temp files left: %v, %v", files, err)
+	}
+}